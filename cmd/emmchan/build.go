@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/certeu/emmchan/emm"
+	"github.com/certeu/emmchan/rss"
+)
+
+// maxPage limits how much of a homepage is read for country inference.
+const maxPage = 1 << 20
+
+// A builder turns inputs into channels of a directory.
+type builder struct {
+	client *emm.Client
+	dir    *emm.Directory
+	inst   *emm.Instance
+	scheme emm.IDScheme
+	// countries infers country and region when set.
+	countries *emm.CountryInferrer
+}
+
+// build fetches the feed of an input and creates its channel, recording
+// the fetch and the outcome of failures in rec. Explicit input values take
+// precedence over inferred ones, which take precedence over the profile
+// defaults.
+func (b *builder) build(ctx context.Context, in *input, rec *record) (*emm.Channel, error) {
+	start := time.Now()
+	body, resp, err := fetch(ctx, in.url, b.client, -1)
+	rec.FetchMs = millis(time.Since(start))
+	if resp != nil {
+		rec.Status = resp.StatusCode
+		rec.Redirects = emm.Redirects(resp)
+		rec.ContentType = resp.Header.Get("Content-Type")
+	}
+	if err != nil {
+		rec.Outcome = fetchError
+		return nil, err
+	}
+	charset := ""
+	if resp != nil {
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			charset = params["charset"]
+		}
+	}
+	rssFeed, err := rss.NewFeedCharset(body, charset)
+	if err != nil {
+		rec.Outcome = parseError
+		return nil, err
+	}
+	rssFeed.Channel.URL = in.url
+	rec.Format, rec.Encoding = rssFeed.Format, rssFeed.Encoding
+	if rec.Encoding == "" {
+		// the XML default
+		rec.Encoding = "UTF-8"
+	}
+
+	c := emm.NewChannelProfile(rssFeed, b.dir.Instance, in.profile)
+	if lang := c.Inferred["language"]; lang != nil && lang.Source == emm.LanguageDetected {
+		log.Printf("Detected language of %s: %s (confidence %.2f)",
+			in.url, lang.Value, lang.Confidence)
+	}
+	if b.countries != nil {
+		b.inferCountry(ctx, c)
+	}
+	in.override.Apply(c)
+	if in.identifier != "" {
+		c.Identifier = in.identifier
+	}
+	c.ID = b.scheme.ID(c, b.dir.Instance)
+	if in.id != "" {
+		c.ID = in.id
+	}
+	rec.Channel = c.ID
+	if err := b.inst.Check(c); err != nil {
+		rec.Outcome = rejectedOutcome
+		return nil, err
+	}
+	return c, nil
+}
+
+func (b *builder) inferCountry(ctx context.Context, c *emm.Channel) {
+	page, _, err := fetch(ctx, c.Identifier, b.client, maxPage)
+	if err != nil {
+		log.Printf("Could not fetch homepage %s: %s", c.Identifier, err)
+	}
+	country, region := b.countries.Infer(c, page)
+	if country.Value == "" {
+		return
+	}
+	if c.Inferred == nil {
+		c.Inferred = make(map[string]*emm.Inference)
+	}
+	c.Inferred["country"] = country
+	c.CountryCode = country.Value
+	if region.Value != "" {
+		c.Region = region.Value
+		c.Inferred["region"] = region
+	}
+	if country.NeedsReview() {
+		log.Printf("Review country of %s: %s (confidence %.2f)",
+			c.Identifier, country.Value, country.Confidence)
+	}
+}
+
+// fetch returns up to max bytes of the body of url, or all of it if max is
+// negative, and the response. Error statuses are errors.
+func fetch(ctx context.Context, url string, client *emm.Client, max int64) ([]byte, *http.Response, error) {
+	resp, err := client.Get(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		// Drain up to 512 bytes and close the body to let
+		// the Transport reuse the connection
+		io.CopyN(ioutil.Discard, resp.Body, 512)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode >= 400 {
+		return nil, resp, fmt.Errorf("HTTP status %s", resp.Status)
+	}
+
+	var r io.Reader = resp.Body
+	if max >= 0 {
+		r = io.LimitReader(r, max)
+	}
+	body, err := ioutil.ReadAll(r)
+	return body, resp, err
+}
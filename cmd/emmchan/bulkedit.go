@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runEdit applies a bulk edit expression. The changes are previewed per
+// channel on STDERR and the directory is written once confirmed on STDIN or
+// with -yes.
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	dir := addDirFlags(fs).writable(fs)
+	dryRun := fs.Bool("dry-run", false, "Only preview the changes")
+	yes := fs.Bool("yes", false, "Write the directory without asking for confirmation")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s edit [flags] 'set field=value[, ...] [where condition]'\n%s", os.Args[0], idNote)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("edit needs an expression")
+	}
+	e, err := emm.ParseEdit(strings.Join(fs.Args(), " "))
+	if err != nil {
+		return err
+	}
+	d, _, err := dir.load()
+	if err != nil {
+		return err
+	}
+	defer dir.close()
+	diffs, err := e.Apply(d)
+	if err != nil {
+		return err
+	}
+	if err := emm.WriteText(os.Stderr, diffs); err != nil {
+		return err
+	}
+	log.Printf("edit: %d channels changed", len(diffs))
+	if *dryRun {
+		return nil
+	}
+	if len(diffs) > 0 && !*yes && !confirm(fmt.Sprintf("Write %d changed channels?", len(diffs))) {
+		return fmt.Errorf("edit not confirmed, directory not written")
+	}
+	return dir.save(d)
+}
+
+// confirm asks question on STDERR and reports whether it was answered yes
+// on STDIN.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// A checkpoint records the inputs processed so far in a run. The partial
+// directory is kept next to it, in a file with the extension .xml added.
+type checkpoint struct {
+	Time time.Time `json:"time"`
+	// Base is the digest of the directory file the run started from and
+	// Saved that of the partial directory when written in place.
+	Base    string    `json:"base"`
+	Saved   string    `json:"saved,omitempty"`
+	Records []*record `json:"records"`
+}
+
+// readCheckpoint reads the checkpoint at path and its partial directory.
+func readCheckpoint(path, inst string) (*checkpoint, *emm.Directory, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(buf, cp); err != nil {
+		return nil, nil, fmt.Errorf("Invalid checkpoint %s: %s", path, err)
+	}
+	d, err := emm.FromFile(path+".xml", inst)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not load the directory of checkpoint %s: %s", path, err)
+	}
+	return cp, d, nil
+}
+
+// resumes reports whether the checkpoint continues a run on the directory
+// file with the given digest.
+func (cp *checkpoint) resumes(digest string) bool {
+	return digest == cp.Base || digest == cp.Saved
+}
+
+// done returns the URLs of the inputs settled, so that failed fetches and
+// parses are retried.
+func (cp *checkpoint) done() map[string]bool {
+	urls := make(map[string]bool)
+	for _, r := range cp.Records {
+		if r.settled() {
+			urls[r.URL] = true
+		}
+	}
+	return urls
+}
+
+// A checkpointer writes checkpoints of a run periodically.
+type checkpointer struct {
+	path string
+	cp   checkpoint
+	rep  *report
+	dir  *emm.Directory
+	stop chan struct{}
+	done chan struct{}
+}
+
+// start writes a checkpoint every interval until halted.
+func (c *checkpointer) start(every time.Duration) {
+	c.stop, c.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(c.done)
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := c.write(); err != nil {
+					log.Printf("Could not write checkpoint: %s", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// halt stops the periodic checkpoints.
+func (c *checkpointer) halt() {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+		c.stop = nil
+	}
+}
+
+// write writes a checkpoint. The records are taken before the directory,
+// so that every input recorded is in the partial directory.
+func (c *checkpointer) write() error {
+	c.cp.Time = time.Now()
+	c.cp.Records = c.rep.snapshot()
+	var buf bytes.Buffer
+	if err := c.dir.Clone().Dump(&buf); err != nil {
+		return err
+	}
+	if err := emm.WriteFile(c.path+".xml", buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	js, err := json.Marshal(c.cp)
+	if err != nil {
+		return err
+	}
+	return emm.WriteFile(c.path, js, 0644)
+}
+
+// remove deletes the checkpoint of a completed run.
+func (c *checkpointer) remove() {
+	os.Remove(c.path)
+	os.Remove(c.path + ".xml")
+}
+
+// fileDigest returns the hex SHA-256 digest of the file at path.
+func fileDigest(path string) (string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// forward sends the inputs read from in to out until in is closed or ctx
+// is done, skipping those done before.
+func forward(ctx context.Context, in <-chan *input, out chan<- *input, done map[string]bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case i, ok := <-in:
+			if !ok {
+				return
+			}
+			if done[i.url] {
+				continue
+			}
+			select {
+			case out <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
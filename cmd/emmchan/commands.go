@@ -0,0 +1,225 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// A command is an emmchan subcommand. It is selected by the first command
+// line argument; without one emmchan adds channels read from STDIN.
+type command struct {
+	// Short description shown in the usage message.
+	short string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"diff":         {"show the channel differences of two directories", runDiff},
+	"disable":      {"disable channels without removing them", runDisable},
+	"edit":         {"bulk edit channels with a set ... where ... expression", runEdit},
+	"enable":       {"enable disabled channels", runEnable},
+	"export":       {"export a channel directory to CSV, JSON or OPML", runExport},
+	"journal":      {"list the journaled changes of a channel directory", runJournal},
+	"list":         {"list channels matching filters", runList},
+	"merge":        {"merge channel directories", runMerge},
+	"merge-driver": {"three-way merge channel directories, for use as git merge driver", runMergeDriver},
+	"migrate":      {"re-ID a channel directory under a new ID scheme", runMigrate},
+	"provenance":   {"show who added and changed channels, when and from which input", runProvenance},
+	"remove":       {"remove channels", runRemove},
+	"remove-feed":  {"remove feeds from channels", runRemoveFeed},
+	"rename":       {"rename a vocabulary term across a channel directory", runRename},
+	"set":          {"set fields of channels", runSet},
+	"undo":         {"undo journaled changes or restore a channel directory to a point in time", runUndo},
+	"validate":     {"check a channel directory for errors", runValidate},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] < urls\n", os.Args[0])
+	fmt.Fprintf(out, "       %s <command> [flags] [args]\n\nFlags:\n", os.Args[0])
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-12s %s\n", name, commands[name].short)
+	}
+}
+
+// dirFlags select the channel directory and its EMM instance and, for
+// commands changing the directory, whether it is written in place.
+type dirFlags struct {
+	path      *string
+	instance  *string
+	instances *string
+	private   *bool
+	vocab     *string
+	inPlace   *bool
+	backups   *int
+	operator  *string
+	// command is journaled as the command changing the directory.
+	command string
+	// loaded is the path of the loaded directory.
+	loaded string
+	// file is the directory file opened for writing in place.
+	file *emm.DirectoryFile
+	// disabled holds the IDs of the channels disabled when loaded, unless
+	// writing in place.
+	disabled map[string]bool
+}
+
+func addDirFlags(fs *flag.FlagSet) *dirFlags {
+	return &dirFlags{
+		path:      fs.String("d", "", "Channel directory file path, defaults to the directory of the instance"),
+		instance:  fs.String("i", "", "Name of the EMM instance, Public by default"),
+		instances: fs.String("instances", "", "Instance definitions file path (JSON)"),
+		private:   fs.Bool("p", false, "Channel directory is for the Private instance, same as -i Private"),
+		vocab:     fs.String("vocabulary", "", "Vocabulary file path (JSON), replaces the instance vocabulary"),
+	}
+}
+
+// idNote is added to the usage of the commands that may change channel IDs.
+const idNote = "Only with -w are the changes journaled and the provenance store updated,\n" +
+	"without it the provenance of channels whose ID changes is left under the old ID.\n"
+
+// writable adds the flags to write the directory in place.
+func (f *dirFlags) writable(fs *flag.FlagSet) *dirFlags {
+	f.inPlace = fs.Bool("w", false, "Write the directory in place instead of to STDOUT, journaling the changes and updating the provenance store")
+	f.backups = fs.Int("backups", emm.DefaultBackups, "Number of backups kept when writing in place")
+	f.operator = fs.String("operator", "", "Operator name journaled with the changes, defaults to the current user")
+	f.command = fs.Name()
+	if fs == flag.CommandLine {
+		f.command = "add"
+	}
+	return f
+}
+
+// load registers the instance definitions and loads the channel directory.
+// When writing in place the directory file is locked until close.
+func (f *dirFlags) load() (*emm.Directory, *emm.Instance, error) {
+	if *f.instances != "" {
+		is, err := emm.InstancesFromFile(*f.instances)
+		if err != nil {
+			return nil, nil, err
+		}
+		emm.RegisterInstances(is)
+	}
+	name := *f.instance
+	switch {
+	case *f.private && name != "":
+		return nil, nil, fmt.Errorf("-p and -i can not be used together")
+	case *f.private:
+		name = "Private"
+	case name == "":
+		name = "Public"
+	}
+	inst, err := emm.LookupInstance(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if *f.vocab != "" {
+		if inst.Vocabulary, err = emm.VocabularyFromFile(*f.vocab); err != nil {
+			return nil, nil, err
+		}
+	}
+	path := *f.path
+	if path == "" {
+		path = inst.Directory
+	}
+	if path == "" {
+		return nil, nil, fmt.Errorf("Could not load channel directory: no path given")
+	}
+	f.loaded = path
+	if f.inPlace != nil && *f.inPlace {
+		var d *emm.Directory
+		if f.file, d, err = emm.OpenFile(path, inst.Name); err != nil {
+			return nil, nil, err
+		}
+		f.file.Backups = *f.backups
+		f.file.Run.Command = f.command
+		f.file.Run.Source = strings.Join(os.Args[1:], " ")
+		if *f.operator != "" {
+			f.file.Run.Operator = *f.operator
+		}
+		return d, inst, nil
+	}
+	d, err := emm.FromFile(path, inst.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	ps, err := emm.ReadProvenance(f.provenance())
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not read the provenance of %s: %s", path, err)
+	}
+	if err := ps.Apply(d); err != nil {
+		return nil, nil, err
+	}
+	f.disabled = make(map[string]bool)
+	for _, c := range d.Channels {
+		if c.Disabled {
+			f.disabled[c.ID] = true
+		}
+	}
+	return d, inst, nil
+}
+
+// save writes the directory in place or to STDOUT. The EMM XML leaves
+// disabled channels out and only the provenance store keeps them, so
+// disabling or enabling channels needs writing in place.
+func (f *dirFlags) save(d *emm.Directory) error {
+	if f.file == nil {
+		for _, c := range d.Channels {
+			if c.Disabled != f.disabled[c.ID] {
+				return fmt.Errorf("Could not write the disabled state of channel %s to STDOUT, use -w", c.ID)
+			}
+		}
+		return d.Dump(os.Stdout)
+	}
+	if err := f.file.Save(d); err != nil {
+		return err
+	}
+	log.Printf("Wrote %s", f.file.Path)
+	return nil
+}
+
+// run returns the journal run of the directory file, nil unless writing in
+// place.
+func (f *dirFlags) run() *emm.JournalRun {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Run
+}
+
+// journal returns the path of the journal of the loaded directory.
+func (f *dirFlags) journal() string {
+	if f.file != nil {
+		return f.file.Journal
+	}
+	return f.loaded + ".journal"
+}
+
+// provenance returns the path of the provenance store of the loaded
+// directory.
+func (f *dirFlags) provenance() string {
+	if f.file != nil {
+		return f.file.Provenance
+	}
+	return f.loaded + ".provenance.json"
+}
+
+// close releases the directory file when writing in place.
+func (f *dirFlags) close() {
+	if f.file != nil {
+		f.file.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Statuses of input lines.
+const (
+	accepted   = "accepted"
+	normalized = "normalized"
+	rejected   = "rejected"
+	ignored    = "ignored"
+)
+
+// A diagnostic accounts for one input line, or one input of a format
+// without lines.
+type diagnostic struct {
+	Line   int    `json:"line,omitempty"`
+	Raw    string `json:"raw"`
+	Status string `json:"status"`
+	// URL is the accepted, possibly normalized, feed URL.
+	URL    string `json:"url,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// diagnostics records the diagnostic of every input line. Normalized and
+// rejected lines are logged, rejected lines added to the run report, and
+// all diagnostics written as JSON lines to the file if one is given.
+type diagnostics struct {
+	sync.Mutex
+	enc    *json.Encoder
+	rep    *report
+	counts map[string]int
+	// closed diagnostics are no longer recorded
+	closed bool
+}
+
+func newDiagnostics(f *os.File, rep *report) *diagnostics {
+	ds := &diagnostics{rep: rep, counts: make(map[string]int)}
+	if f != nil {
+		ds.enc = json.NewEncoder(f)
+	}
+	return ds
+}
+
+func (ds *diagnostics) record(d diagnostic) {
+	ds.Lock()
+	defer ds.Unlock()
+	if ds.closed {
+		return
+	}
+	ds.counts[d.Status]++
+	prefix := ""
+	if d.Line > 0 {
+		prefix = fmt.Sprintf("Line %d: ", d.Line)
+	}
+	switch d.Status {
+	case normalized:
+		log.Printf("%sNormalized %q to %s", prefix, d.Raw, d.URL)
+	case rejected:
+		log.Printf("%sSkipping %q: %s", prefix, d.Raw, d.Reason)
+		ds.rep.add(&record{Line: d.Line, URL: d.Raw, Outcome: invalid, Error: d.Reason})
+	}
+	if ds.enc != nil {
+		if err := ds.enc.Encode(d); err != nil {
+			log.Printf("Could not write diagnostic: %s", err)
+		}
+	}
+}
+
+// close stops recording diagnostics, so that the file they are written to
+// can be closed while the input is still read, and logs the number of
+// lines per status, if any lines were read.
+func (ds *diagnostics) close() {
+	ds.Lock()
+	defer ds.Unlock()
+	ds.closed = true
+	n := 0
+	for _, c := range ds.counts {
+		n += c
+	}
+	if n == 0 {
+		return
+	}
+	log.Printf("Read %d inputs: %d accepted, %d normalized, %d rejected, %d ignored",
+		n, ds.counts[accepted], ds.counts[normalized], ds.counts[rejected], ds.counts[ignored])
+}
+
+// stripComment removes a comment from an input line. Comments start with #
+// at the beginning of the line or after white space, so that URL fragments
+// are kept.
+func stripComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
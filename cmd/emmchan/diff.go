@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runDiff compares two channel directories and writes the differences to
+// STDOUT.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("f", "text", "Output format: text, json or unified")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diff [flags] old.xml new.xml\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("diff needs two channel directories")
+	}
+
+	old, err := emm.FromFile(fs.Arg(0), "")
+	if err != nil {
+		return err
+	}
+	new, err := emm.FromFile(fs.Arg(1), "")
+	if err != nil {
+		return err
+	}
+	diffs := emm.Diff(old, new)
+
+	switch *format {
+	case "text":
+		return emm.WriteText(os.Stdout, diffs)
+	case "json":
+		if diffs == nil {
+			diffs = []*emm.ChannelDiff{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diffs)
+	case "unified":
+		return emm.WriteUnified(os.Stdout, fs.Arg(0), fs.Arg(1), diffs)
+	}
+	return fmt.Errorf("Unknown output format %q", *format)
+}
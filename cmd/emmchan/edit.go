@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// listFlag is a flag that may be given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// editFlags select the channels to edit and whether the edit is only
+// previewed.
+type editFlags struct {
+	dir    *dirFlags
+	sel    emm.Selector
+	dryRun *bool
+}
+
+func addEditFlags(fs *flag.FlagSet) *editFlags {
+	f := &editFlags{
+		dir:    addDirFlags(fs).writable(fs),
+		dryRun: fs.Bool("dry-run", false, "Write the changes as unified diff instead of the directory"),
+	}
+	fs.Var((*listFlag)(&f.sel.IDs), "id", "Select channels by ID (repeatable)")
+	fs.Var((*listFlag)(&f.sel.Identifiers), "identifier", "Select channels by identifier (repeatable)")
+	fs.Var((*listFlag)(&f.sel.Feeds), "feed", "Select channels by feed URL (repeatable)")
+	return f
+}
+
+// edit loads the directory and applies fn to it. The edited directory is
+// saved or, on a dry run, the changes written as unified diff.
+func (f *editFlags) edit(name string, fn func(d *emm.Directory) (emm.Channels, error)) error {
+	d, _, err := f.dir.load()
+	if err != nil {
+		return err
+	}
+	defer f.dir.close()
+	before := d.Clone()
+	changed, err := fn(d)
+	if err != nil {
+		return err
+	}
+	seen := make(map[*emm.Channel]bool)
+	for _, c := range changed {
+		seen[c] = true
+	}
+	log.Printf("%s: %d channels changed", name, len(seen))
+	if *f.dryRun {
+		return emm.WriteUnified(os.Stdout, "before", "after", emm.Diff(before, d))
+	}
+	return f.dir.save(d)
+}
+
+// runRemove removes the selected channels.
+func runRemove(args []string) error {
+	fs := flag.NewFlagSet("remove", flag.ExitOnError)
+	ef := addEditFlags(fs)
+	fs.Parse(args)
+	if ef.sel.Empty() {
+		fs.Usage()
+		return fmt.Errorf("remove needs -id, -identifier or -feed")
+	}
+	return ef.edit("remove", func(d *emm.Directory) (emm.Channels, error) {
+		return d.Remove(ef.sel), nil
+	})
+}
+
+// runRemoveFeed removes feeds from multi-feed channels.
+func runRemoveFeed(args []string) error {
+	fs := flag.NewFlagSet("remove-feed", flag.ExitOnError)
+	ef := addEditFlags(fs)
+	fs.Parse(args)
+	if len(ef.sel.Feeds) == 0 {
+		fs.Usage()
+		return fmt.Errorf("remove-feed needs -feed")
+	}
+	return ef.edit("remove-feed", func(d *emm.Directory) (emm.Channels, error) {
+		var changed emm.Channels
+		for _, u := range ef.sel.Feeds {
+			changed = append(changed, d.RemoveFeed(u)...)
+		}
+		return changed, nil
+	})
+}
+
+// runSet sets fields of the selected channels.
+func runSet(args []string) error {
+	fs := flag.NewFlagSet("set", flag.ExitOnError)
+	ef := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s set [flags] field=value ...\n%s", os.Args[0], idNote)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if ef.sel.Empty() || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("set needs -id, -identifier or -feed and a field=value")
+	}
+	return ef.edit("set", func(d *emm.Directory) (emm.Channels, error) {
+		var changed emm.Channels
+		for _, a := range fs.Args() {
+			kv := strings.SplitN(a, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("Invalid assignment %q, want field=value", a)
+			}
+			cs, err := d.SetField(ef.sel, kv[0], kv[1])
+			if err != nil {
+				return nil, err
+			}
+			changed = append(changed, cs...)
+		}
+		return changed, nil
+	})
+}
+
+// runDisable disables the selected channels.
+func runDisable(args []string) error {
+	return setDisabled("disable", args, true)
+}
+
+// runEnable enables the selected channels.
+func runEnable(args []string) error {
+	return setDisabled("enable", args, false)
+}
+
+func setDisabled(name string, args []string, disabled bool) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	ef := addEditFlags(fs)
+	fs.Parse(args)
+	if ef.sel.Empty() {
+		fs.Usage()
+		return fmt.Errorf("%s needs -id, -identifier or -feed", name)
+	}
+	return ef.edit(name, func(d *emm.Directory) (emm.Channels, error) {
+		return d.SetDisabled(ef.sel, disabled), nil
+	})
+}
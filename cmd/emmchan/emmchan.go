@@ -4,126 +4,438 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
-	"net/url"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/certeu/emmchan/emm"
-	"github.com/certeu/emmchan/rss"
 )
 
 var buildInfo string
 
 var (
-	chDir   = flag.String("d", "", "Channel directory file path")
-	private = flag.Bool("p", false, "Channel is for a private instance")
-	version = flag.Bool("v", false, "Display version and exit")
+	dirs      = addDirFlags(flag.CommandLine).writable(flag.CommandLine)
+	scheme    = flag.String("s", "", "ID scheme for new channels, e.g. {country}_{domain}")
+	profFile  = flag.String("profiles", "", "Channel profiles file path (JSON)")
+	profName  = flag.String("profile", "", "Name of the profile used for new channels")
+	infer     = flag.Bool("infer", false, "Infer country and region of new channels")
+	ctryFile  = flag.String("countries", "", "Country and region table file path (JSON)")
+	format    = flag.String("format", "lines", "Input format: lines, opml, csv, tsv or harvest")
+	foldFile  = flag.String("folders", "", "Profiles by OPML folder name file path (JSON)")
+	repFile   = flag.String("report", "", "File path to write the run report to")
+	repFormat = flag.String("report-format", "json", "Run report format: json or jsonl")
+	diagFile  = flag.String("diagnostics", "", "File path to write the diagnostic of every input line to (JSON lines)")
+	dryRun    = flag.Bool("dry-run", false, "Run the whole pipeline but write the changes that would be made instead of the directory")
+	ckFile    = flag.String("checkpoint", "", "File path to checkpoint the run to, so that it can be resumed")
+	ckEvery   = flag.Duration("checkpoint-every", 30*time.Second, "Interval between checkpoints")
+	resume    = flag.Bool("resume", false, "Resume the run checkpointed with -checkpoint, skipping the inputs done")
+	timeout   = flag.Duration("timeout", emm.DefaultTimeout, "Timeout of each HTTP request, 0 for none")
+	deadline  = flag.Duration("deadline", 0, "Time limit of the whole run, 0 for none")
+	version   = flag.Bool("v", false, "Display version and exit")
 )
 
-func getFeed(feedURL string, client *emm.Client) (*rss.Feed, error) {
-	resp, err := client.Get(feedURL)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		// Drain up to 512 bytes and close the body to let
-		// the Transport reuse the connection
-		io.CopyN(ioutil.Discard, resp.Body, 512)
-		resp.Body.Close()
-	}()
+// An input is a feed URL read from STDIN with the metadata for its channel.
+type input struct {
+	url string
+	// line is the input line number, if any.
+	line int
+	// id overrides the ID given by the ID scheme.
+	id string
+	// identifier overrides the homepage link of the feed.
+	identifier string
+	// profile holds the defaults for the new channel.
+	profile *emm.Profile
+	// override holds the values given explicitly on the input line.
+	override *emm.Profile
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// parseLine parses an input line of the form "URL [field=value ...]". The
+// field profile selects a profile from ps to be merged over base, any other
+// channel field overrides the profile value. def is the profile used when
+// none is selected.
+func parseLine(line string, ps emm.Profiles, base, def *emm.Profile) (*input, error) {
+	fields := strings.Fields(line)
+	u, _, err := emm.NormalizeURL(fields[0])
 	if err != nil {
 		return nil, err
 	}
-	rssFeed, err := rss.NewFeed(body)
-	if err != nil {
-		return nil, err
+	in := &input{url: u, profile: def, override: &emm.Profile{}}
+	for _, f := range fields[1:] {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("Invalid field %q, want field=value", f)
+		}
+		if err := in.set(kv[0], kv[1], ps, base); err != nil {
+			return nil, err
+		}
 	}
-	rssFeed.Channel.URL = feedURL
-	return rssFeed, nil
+	return in, nil
 }
 
-func processChannel(inCh chan string, c *emm.Client, d *emm.Directory, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for u := range inCh {
-		rssFeed, err := getFeed(u, c)
+// set sets an explicit input value. The field profile selects a profile
+// from ps to be merged over base, id and identifier override the channel
+// ID and homepage, any other channel field overrides the profile value.
+func (in *input) set(field, value string, ps emm.Profiles, base *emm.Profile) error {
+	switch field {
+	case "profile":
+		p, err := ps.Get(value, base)
 		if err != nil {
-			log.Printf("Error in %s: %s", u, err)
-		} else {
-			emmCh := emm.NewChannel(rssFeed, d.Instance)
-			d.Add(emmCh)
+			return err
 		}
+		in.profile = p
+		return nil
+	case "id":
+		in.id = value
+		return nil
+	case "identifier":
+		u, _, err := emm.NormalizeURL(value)
+		if err != nil {
+			return err
+		}
+		in.identifier = u
+		return nil
 	}
+	return in.override.Set(field, value)
 }
 
-func validInput(in string) error {
-	u, err := url.Parse(in)
-	if err != nil {
-		return fmt.Errorf("Could not parse URL: %s", err)
+// send sends in to out unless ctx is done first.
+func send(ctx context.Context, out chan<- *input, in *input) error {
+	select {
+	case out <- in:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readLines sends the inputs parsed from the lines of r to out until ctx is
+// done and records the diagnostic of every line in ds. Blank lines and
+// comments are ignored, invalid lines rejected.
+func readLines(ctx context.Context, r io.Reader, ps emm.Profiles, base, def *emm.Profile, out chan<- *input, ds *diagnostics) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		d := diagnostic{Line: n, Raw: sc.Text(), Status: ignored}
+		line := stripComment(sc.Text())
+		if strings.TrimSpace(line) == "" {
+			d.Reason = "blank line"
+			if line != sc.Text() {
+				d.Reason = "comment"
+			}
+			ds.record(d)
+			continue
+		}
+		in, err := parseLine(line, ps, base, def)
+		if err != nil {
+			d.Status, d.Reason = rejected, err.Error()
+			ds.record(d)
+			continue
+		}
+		d.Status, d.URL = accepted, in.url
+		if in.url != strings.Fields(line)[0] {
+			d.Status = normalized
+		}
+		ds.record(d)
+		in.line = n
+		if err := send(ctx, out, in); err != nil {
+			return err
+		}
 	}
-	if u.Scheme == "" || u.Host == "" || u.Path == "" {
-		return fmt.Errorf("Invalid URL %s", u)
+	return sc.Err()
+}
+
+// processChannel builds and inserts the channels of the inputs read from
+// inCh. Inputs canceled with ctx are not recorded, so that a resumed run
+// processes them again.
+func processChannel(ctx context.Context, inCh chan *input, b *builder, rep *report, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for in := range inCh {
+		start := time.Now()
+		rec := &record{Line: in.line, URL: in.url}
+		emmCh, err := b.build(ctx, in, rec)
+		if err != nil && ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error in %s: %s", in.url, err)
+			rec.Error = err.Error()
+		} else {
+			rec.Outcome, rec.ch = b.dir.Insert(emmCh)
+			rec.Channel = rec.ch.ID
+		}
+		rec.TotalMs = millis(time.Since(start))
+		rep.add(rec)
 	}
-	return nil
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	flag.Usage = usage
 	flag.Parse()
 	if *version {
 		fmt.Printf("Version: %s\n", buildInfo)
 		return
 	}
-	if *chDir == "" {
-		fmt.Printf("Could not load channel directory\n")
+	d, inst, err := dirs.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	inst := "Public"
-	if *private {
-		inst = "Private"
+	s := emm.DefaultIDScheme
+	if *scheme != "" {
+		s = emm.IDScheme(*scheme)
+		if err := s.Validate(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	d, err := emm.FromFile(*chDir, inst)
-	if err != nil {
-		log.Fatal(err)
+	var ps emm.Profiles
+	base := inst.Defaults(emm.DefaultProfile)
+	prof := base
+	if *profFile != "" {
+		if ps, err = emm.ProfilesFromFile(*profFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *profName != "" {
+		if prof, err = ps.Get(*profName, base); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var folders emm.Profiles
+	switch *format {
+	case "lines", "csv", "tsv", "harvest":
+	case "opml":
+		if *foldFile != "" {
+			if folders, err = emm.ProfilesFromFile(*foldFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+	default:
+		log.Fatalf("Unknown input format %q", *format)
+	}
+	if *resume && *ckFile == "" {
+		log.Fatal("-resume needs -checkpoint")
+	}
+	if *dryRun && *ckFile != "" {
+		log.Fatal("-checkpoint can not be used with -dry-run")
 	}
 
 	log.Printf("Loaded channel directory with %d channels", len(d.Channels))
 
 	var wg sync.WaitGroup
-	urls := make(chan string)
+	urls := make(chan *input)
 	client := emm.NewClient(nil)
+	client.Timeout = *timeout
+	b := &builder{
+		client: client,
+		dir:    d,
+		inst:   inst,
+		scheme: s,
+	}
+	if *infer || *ctryFile != "" {
+		b.countries = &emm.CountryInferrer{}
+		if *ctryFile != "" {
+			if b.countries, err = emm.CountryInferrerFromFile(*ctryFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
 
-	for i := 0; i < 100; i++ {
-		wg.Add(1)
-		go processChannel(urls, client, d, &wg)
+	var diagOut, repOut *os.File
+	if *diagFile != "" {
+		if diagOut, err = os.Create(*diagFile); err != nil {
+			log.Fatal(err)
+		}
 	}
+	if *repFile != "" {
+		if *repFormat != "json" && *repFormat != "jsonl" {
+			log.Fatalf("Unknown report format %q", *repFormat)
+		}
+		if repOut, err = os.Create(*repFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var before *emm.Directory
+	if *dryRun {
+		before = d.Clone()
+	}
+	rep := newReport()
+	ds := newDiagnostics(diagOut, rep)
 
-	s := bufio.NewScanner(os.Stdin)
-	for s.Scan() {
-		if s.Text() == "" {
-			continue
+	var ck *checkpointer
+	var done map[string]bool
+	if *ckFile != "" {
+		digest, err := fileDigest(dirs.loaded)
+		if err != nil {
+			log.Fatal(err)
 		}
-		if err := validInput(s.Text()); err == nil {
-			urls <- s.Text()
+		ck = &checkpointer{path: *ckFile, cp: checkpoint{Base: digest}, rep: rep, dir: d}
+		if *resume {
+			cp, partial, err := readCheckpoint(*ckFile, inst.Name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !cp.resumes(digest) {
+				log.Fatalf("Channel directory %s changed since checkpoint %s", dirs.loaded, *ckFile)
+			}
+			// the partial directory leaves the disabled channels out
+			for _, c := range d.Channels {
+				if c.Disabled {
+					partial.Channels = append(partial.Channels, c)
+				}
+			}
+			d.Channels = partial.Channels
+			rep.restore(cp.Records)
+			done = cp.done()
+			ck.cp.Base, ck.cp.Saved = cp.Base, cp.Saved
+			log.Printf("Resuming run checkpointed at %s with %d inputs done", cp.Time.Format(time.RFC3339), len(done))
 		}
+		ck.start(*ckEvery)
 	}
 
-	if err := s.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "reading standard input:", err)
+	// the run is canceled on the first SIGINT or SIGTERM, and at the
+	// deadline if given
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if *deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *deadline)
+		defer cancel()
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sig:
+			log.Printf("Interrupted, canceling the inputs in progress")
+			signal.Stop(sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go processChannel(ctx, urls, b, rep, &wg)
 	}
 
+	// inputs are read in the background, so that a signal stops the run
+	// even while reading blocks
+	inputs := make(chan *input)
+	go func() {
+		var err error
+		switch *format {
+		case "lines":
+			err = readLines(ctx, os.Stdin, ps, base, prof, inputs, ds)
+		case "opml":
+			err = readOPML(ctx, os.Stdin, folders, prof, inputs, ds)
+		case "csv":
+			err = readTable(ctx, os.Stdin, ',', ps, base, prof, inputs, ds)
+		case "tsv":
+			err = readTable(ctx, os.Stdin, '\t', ps, base, prof, inputs, ds)
+		case "harvest":
+			err = readHarvest(ctx, os.Stdin, prof, inputs, ds)
+		}
+		if err != nil && err != ctx.Err() {
+			log.Printf("Reading standard input: %s", err)
+		}
+		close(inputs)
+	}()
+	forward(ctx, inputs, urls, done)
 	close(urls)
 	wg.Wait()
+	// the reader may still block on STDIN when interrupted
+	ds.close()
+	if diagOut != nil {
+		diagOut.Close()
+	}
+	interrupted := ctx.Err() != nil
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Run deadline of %s exceeded", *deadline)
+	}
+	if ck != nil {
+		ck.halt()
+		if interrupted {
+			if err := ck.write(); err != nil {
+				log.Fatalf("Could not write checkpoint: %s", err)
+			}
+		}
+	}
 
-	if err := d.Dump(os.Stdout); err != nil {
-		log.Fatal(err)
+	if *dryRun {
+		diffs := emm.Diff(before, d)
+		log.Printf("Dry run: %d channels would be changed", len(diffs))
+		if err := rep.writeText(os.Stdout, d); err != nil {
+			log.Fatal(err)
+		}
+		if err := emm.WriteUnified(os.Stdout, "before", "after", diffs); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		if run := dirs.run(); run != nil {
+			run.Source = fmt.Sprintf("stdin (%s)", *format)
+			rep.journal(run, d)
+		}
+		if err := dirs.save(d); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if ck != nil {
+		if !interrupted {
+			ck.remove()
+		} else if dirs.file != nil {
+			// a resumed run may start from the partial directory saved
+			if ck.cp.Saved, err = fileDigest(dirs.loaded); err == nil {
+				err = ck.write()
+			}
+			if err != nil {
+				log.Fatalf("Could not write checkpoint: %s", err)
+			}
+		}
+	}
+	dirs.close()
+
+	sum := rep.summary()
+	log.Printf("Processed %s", sum)
+	if repOut != nil {
+		if err := rep.write(repOut, *repFormat == "jsonl"); err != nil {
+			log.Fatalf("Could not write report: %s", err)
+		}
+		repOut.Close()
+	}
+	if interrupted {
+		switch {
+		case ck != nil:
+			log.Printf("Run interrupted, resume with -resume")
+		case *dryRun:
+			log.Printf("Run interrupted, the dry run covers the inputs done")
+		default:
+			log.Printf("Run interrupted, wrote the directory with the inputs done")
+		}
+		if ctx.Err() == context.Canceled {
+			os.Exit(130)
+		}
+		os.Exit(2)
+	}
+	if sum.Failed > 0 {
+		os.Exit(2)
 	}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runExport exports the channel directory to CSV, JSON or OPML.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dir := addDirFlags(fs)
+	format := fs.String("f", "csv", "Output format: csv, json, jsonl or opml")
+	columns := fs.String("columns", strings.Join(emm.ExportColumns, ","), "Comma separated columns of csv output, channel fields or feeds")
+	group := fs.String("group", "category", "Channel field by which opml outlines are grouped, none for no grouping")
+	title := fs.String("title", "EMM channels", "Title of opml output")
+	fs.Parse(args)
+
+	d, _, err := dir.load()
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "csv":
+		return d.ExportCSV(os.Stdout, strings.Split(*columns, ","))
+	case "json":
+		return d.ExportJSON(os.Stdout)
+	case "jsonl":
+		return d.ExportJSONLines(os.Stdout)
+	case "opml":
+		if *group == "none" {
+			*group = ""
+		}
+		return d.ExportOPML(os.Stdout, *title, *group)
+	}
+	return fmt.Errorf("Unknown output format %q", *format)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// readHarvest sends an input for every URL harvested from text, HTML,
+// e-mail or bookmark files read from r to out until ctx is done. def is the
+// profile of the new channels.
+func readHarvest(ctx context.Context, r io.Reader, def *emm.Profile, out chan<- *input, ds *diagnostics) error {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	urls := emm.HarvestURLs(buf)
+	log.Printf("Harvested %d URLs", len(urls))
+	for _, u := range urls {
+		n, changed, err := emm.NormalizeURL(u)
+		d := diagnostic{Raw: u, Status: accepted, URL: n}
+		if changed {
+			d.Status = normalized
+		}
+		if err != nil {
+			d.Status, d.URL, d.Reason = rejected, "", err.Error()
+		}
+		ds.record(d)
+		if err != nil {
+			continue
+		}
+		if err := send(ctx, out, &input{url: n, profile: def, override: &emm.Profile{}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runList lists the channels matching all given filters.
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	dir := addDirFlags(fs)
+	format := fs.String("f", "table", "Output format: table, jsonl, csv or xml")
+	columns := fs.String("columns", "id,country,language,category,identifier", "Comma separated columns of table and csv output, channel fields or feeds")
+	var eq, prefix, regex, feeds listFlag
+	fs.Var(&eq, "eq", "Filter field=value, exact match (repeatable)")
+	fs.Var(&prefix, "prefix", "Filter field=prefix (repeatable)")
+	fs.Var(&regex, "regex", "Filter field=regular expression (repeatable)")
+	fs.Var(&feeds, "feed", "Filter channels with the feed URL (repeatable)")
+	where := fs.String("where", "", "Filter condition, as in the where clause of edit")
+	fs.Parse(args)
+
+	var filters []emm.Filter
+	for _, fl := range []struct {
+		op   string
+		args []string
+	}{{"=", eq}, {"prefix", prefix}, {"~", regex}} {
+		for _, a := range fl.args {
+			kv := strings.SplitN(a, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("Invalid filter %q, want field=value", a)
+			}
+			f, err := emm.FieldFilter(kv[0], fl.op, kv[1])
+			if err != nil {
+				return err
+			}
+			filters = append(filters, f)
+		}
+	}
+	for _, u := range feeds {
+		f, _ := emm.FieldFilter("feed", "=", u)
+		filters = append(filters, f)
+	}
+	if *where != "" {
+		f, err := emm.ParseFilter(*where)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+
+	d, _, err := dir.load()
+	if err != nil {
+		return err
+	}
+	cs := d.Filter(emm.All(filters...))
+	cols := strings.Split(*columns, ",")
+	switch *format {
+	case "table":
+		return emm.WriteTable(os.Stdout, cs, cols)
+	case "jsonl":
+		return emm.WriteJSONLines(os.Stdout, cs)
+	case "csv":
+		return emm.WriteCSV(os.Stdout, cs, cols)
+	case "xml":
+		return emm.WriteXML(os.Stdout, cs)
+	}
+	return fmt.Errorf("Unknown output format %q", *format)
+}
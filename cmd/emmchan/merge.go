@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runMerge merges channel directories and writes the result to STDOUT.
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	policy := fs.String("policy", emm.FirstWins, "Conflict policy: first-wins, last-wins, field-precedence or fail")
+	match := fs.String("match", "id,identifier,feed", "Comma separated keys channels are matched by")
+	prec := fs.String("precedence", "", "Comma separated field=N pairs, N being the position of the directory whose value wins")
+	report := fs.String("report", "", "Write the conflict report as JSON to this file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s merge [flags] a.xml b.xml ...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("merge needs at least two channel directories")
+	}
+
+	opts := emm.MergeOptions{
+		Policy:     *policy,
+		MatchBy:    strings.Split(*match, ","),
+		Precedence: make(map[string]int),
+	}
+	if *prec != "" {
+		for _, p := range strings.Split(*prec, ",") {
+			kv := strings.SplitN(p, "=", 2)
+			n, err := strconv.Atoi(kv[len(kv)-1])
+			if len(kv) != 2 || err != nil || n < 1 || n > fs.NArg() {
+				return fmt.Errorf("Invalid precedence %q, want field=N with N from 1 to %d", p, fs.NArg())
+			}
+			opts.Precedence[kv[0]] = n - 1
+		}
+	}
+
+	var dirs []*emm.Directory
+	for _, path := range fs.Args() {
+		d, err := emm.FromFile(path, "")
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, d)
+	}
+
+	d, conflicts, err := emm.Merge(opts, dirs...)
+	for _, c := range conflicts {
+		log.Printf("Conflict in %s", c)
+	}
+	if *report != "" {
+		r := struct {
+			// Sources lists the directories by conflict value source index.
+			Sources   []string       `json:"sources"`
+			Conflicts []emm.Conflict `json:"conflicts"`
+		}{fs.Args(), conflicts}
+		if r.Conflicts == nil {
+			r.Conflicts = []emm.Conflict{}
+		}
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*report, out, 0644); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return d.Dump(os.Stdout)
+}
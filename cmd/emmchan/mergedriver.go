@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+var sourceNames = []string{"base", "ours", "theirs"}
+
+// runMergeDriver is a git merge driver for channel directories. It merges
+// base (%O), ours (%A) and theirs (%B), writes the result to ours and fails
+// only if the same field was changed both ways. Channels removed on one side
+// and modified on the other are kept and reported.
+func runMergeDriver(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("Usage: %s merge-driver base ours theirs", os.Args[0])
+	}
+	var dirs []*emm.Directory
+	for _, path := range args {
+		d, err := emm.FromFile(path, "")
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, d)
+	}
+
+	d, conflicts := emm.Merge3(dirs[0], dirs[1], dirs[2])
+
+	f, err := os.Create(args[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := d.Dump(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	n := 0
+	for _, c := range conflicts {
+		if c.Field == emm.ChannelConflict {
+			fmt.Fprintf(os.Stderr, "KEPT channel %s:", c.Channel)
+		} else {
+			fmt.Fprintf(os.Stderr, "CONFLICT channel %s, %s:", c.Channel, c.Field)
+			n++
+		}
+		for _, v := range c.Values {
+			fmt.Fprintf(os.Stderr, " %s=%q", sourceNames[v.Source], v.Value)
+		}
+		fmt.Fprintf(os.Stderr, ", kept %q\n", c.Chosen)
+	}
+	if n > 0 {
+		return fmt.Errorf("%d merge conflicts in %s", n, args[1])
+	}
+	return nil
+}
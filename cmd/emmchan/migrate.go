@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runMigrate re-IDs a channel directory under a new ID scheme. The new
+// directory is saved and the old to new ID mapping, if requested, written
+// to a CSV file.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	df := addDirFlags(fs).writable(fs)
+	scheme := fs.String("s", "", "ID scheme, e.g. {country}_{domain}")
+	mapping := fs.String("m", "", "Write the old to new ID mapping as CSV to this file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate [flags]\n%s", os.Args[0], idNote)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *scheme == "" {
+		fs.Usage()
+		return fmt.Errorf("migrate needs an ID scheme")
+	}
+	s := emm.IDScheme(*scheme)
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	d, _, err := df.load()
+	if err != nil {
+		return err
+	}
+	defer df.close()
+	changes := d.Migrate(s)
+
+	if *mapping != "" {
+		if err := writeMapping(*mapping, changes); err != nil {
+			return err
+		}
+	}
+	return df.save(d)
+}
+
+func writeMapping(path string, changes []emm.IDChange) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"old", "new"})
+	for _, c := range changes {
+		w.Write([]string{c.Old, c.New})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
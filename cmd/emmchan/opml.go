@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+
+	"github.com/certeu/emmchan/emm"
+	"github.com/certeu/emmchan/opml"
+)
+
+// readOPML sends an input for every feed outline of an OPML document to
+// out until ctx is done. The outline title, language and htmlUrl are taken as explicit
+// channel values, over those of the profiles of its enclosing folders in
+// folders, innermost last. def is the profile of the new channels.
+func readOPML(ctx context.Context, r io.Reader, folders emm.Profiles, def *emm.Profile, out chan<- *input, ds *diagnostics) error {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	doc, err := opml.NewOPML(buf)
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	doc.Walk(func(path []string, o *opml.Outline) {
+		if o.XMLURL == "" || sendErr != nil {
+			return
+		}
+		d := diagnostic{Raw: o.XMLURL}
+		u, changed, err := emm.NormalizeURL(o.XMLURL)
+		if err != nil {
+			d.Status, d.Reason = rejected, err.Error()
+			ds.record(d)
+			return
+		}
+		d.Status, d.URL = accepted, u
+		if changed {
+			d.Status = normalized
+		}
+		ds.record(d)
+		in := &input{url: u, identifier: o.HTMLURL, profile: def, override: &emm.Profile{}}
+		for _, name := range path {
+			in.override = in.override.Merge(folders[name])
+		}
+		title := o.Title
+		if title == "" {
+			title = o.Text
+		}
+		in.override.Description = title
+		if o.Language != "" {
+			lang, err := emm.NormalizeLanguage(o.Language)
+			if err != nil {
+				log.Printf("Ignoring language of outline %q: %s", o.Text, err)
+			} else {
+				in.override.Language = lang
+			}
+		}
+		sendErr = send(ctx, out, in)
+	})
+	return sendErr
+}
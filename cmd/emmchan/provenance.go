@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runProvenance writes the provenance of channels, by default of all
+// channels of the directory.
+func runProvenance(args []string) error {
+	fs := flag.NewFlagSet("provenance", flag.ExitOnError)
+	dir := addDirFlags(fs)
+	format := fs.String("f", "table", "Output format: table or jsonl")
+	var ids listFlag
+	fs.Var(&ids, "id", "Channel ID, current or before a migration (repeatable)")
+	by := fs.String("by", "", "Only channels added or modified by this operator")
+	removed := fs.Bool("removed", false, "Include removed channels")
+	fs.Parse(args)
+
+	d, _, err := dir.load()
+	if err != nil {
+		return err
+	}
+	store, err := emm.ReadProvenance(dir.provenance())
+	if err != nil {
+		return err
+	}
+
+	var ps []*emm.Provenance
+	if len(ids) > 0 {
+		for _, id := range ids {
+			p := store.Lookup(id)
+			if p == nil {
+				return fmt.Errorf("No provenance of channel %s", id)
+			}
+			ps = append(ps, p)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, c := range d.Channels {
+			seen[c.ID] = true
+			if p, ok := store[c.ID]; ok {
+				ps = append(ps, p)
+			} else {
+				ps = append(ps, &emm.Provenance{ID: c.ID})
+			}
+		}
+		var gone []*emm.Provenance
+		for id, p := range store {
+			if *removed && !seen[id] {
+				gone = append(gone, p)
+			}
+		}
+		sort.Slice(gone, func(i, j int) bool { return gone[i].ID < gone[j].ID })
+		ps = append(ps, gone...)
+	}
+	if *by != "" {
+		var mine []*emm.Provenance
+		for _, p := range ps {
+			if p.AddedBy == *by || p.ModifiedBy == *by {
+				mine = append(mine, p)
+			}
+		}
+		ps = mine
+	}
+
+	switch *format {
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		for _, p := range ps {
+			if err := enc.Encode(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "id\tadded\taddedBy\tmodified\tmodifiedBy\tchecked\tremoved\tsource")
+		for _, p := range ps {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, formatTime(p.Added), p.AddedBy,
+				formatTime(p.Modified), p.ModifiedBy, formatTime(p.Checked), formatTime(p.Removed), p.Source)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("Unknown format %q", *format)
+}
+
+// formatTime formats an optional time in local time, "-" if unknown.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
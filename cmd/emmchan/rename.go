@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runRename renames a vocabulary term on every channel of a directory and
+// saves the directory. When writing in place the term is renamed in the
+// vocabulary file as well.
+func runRename(args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ExitOnError)
+	df := addDirFlags(fs).writable(fs)
+	field := fs.String("field", "category", "Channel field of the term")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s rename [flags] old new\n%s", os.Args[0], idNote)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("rename needs the old and the new term")
+	}
+
+	d, inst, err := df.load()
+	if err != nil {
+		return err
+	}
+	defer df.close()
+	old, new := fs.Arg(0), fs.Arg(1)
+	vocab := inst.Vocabulary
+	path := *df.vocab
+	if path == "" {
+		path = inst.VocabularyFile
+	}
+	// the vocabulary file is only changed along with the directory file
+	terms, ok := vocab[*field]
+	inVocab := ok && path != "" && vocab.Allowed(*field, old)
+	if !inVocab && !vocab.Allowed(*field, new) {
+		return fmt.Errorf("%s %q is not in the vocabulary", *field, new)
+	}
+	n, err := d.RenameTerm(*field, old, new)
+	if err != nil {
+		return err
+	}
+	log.Printf("Renamed %s %q to %q in %d channels", *field, old, new, n)
+	if err := df.save(d); err != nil {
+		return err
+	}
+	if !inVocab {
+		return nil
+	}
+	if !*df.inPlace {
+		log.Printf("Vocabulary %s left as is, use -w to rename the term in it as well", path)
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	vocab.Rename(*field, old, new)
+	var buf bytes.Buffer
+	if err := vocab.Dump(&buf); err != nil {
+		vocab[*field] = terms
+		return err
+	}
+	if err := emm.WriteFile(path, buf.Bytes(), info.Mode()); err != nil {
+		vocab[*field] = terms
+		return err
+	}
+	log.Printf("Renamed %s %q to %q in vocabulary %s", *field, old, new, path)
+	return nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// Outcomes of inputs besides those of emm.Directory.Insert.
+const (
+	// invalid inputs are rejected before fetching.
+	invalid    = "invalid"
+	fetchError = "fetch-error"
+	parseError = "parse-error"
+	// rejectedOutcome channels violate the policy of the instance.
+	rejectedOutcome = "rejected"
+)
+
+// A record reports what became of one input.
+type record struct {
+	Line        int      `json:"line,omitempty"`
+	URL         string   `json:"url"`
+	Outcome     string   `json:"outcome"`
+	Error       string   `json:"error,omitempty"`
+	Status      int      `json:"status,omitempty"`
+	Redirects   []string `json:"redirects,omitempty"`
+	ContentType string   `json:"contentType,omitempty"`
+	Format      string   `json:"format,omitempty"`
+	Encoding    string   `json:"encoding,omitempty"`
+	Channel     string   `json:"channel,omitempty"`
+	// timings in milliseconds
+	FetchMs int64 `json:"fetchMs,omitempty"`
+	TotalMs int64 `json:"totalMs"`
+	// ch is the channel of the directory the input ended up in, unknown
+	// for records restored from a checkpoint
+	ch *emm.Channel
+}
+
+// settled reports whether the input was processed for good: it ended up in
+// the directory or was rejected by the instance policy. Other failures may
+// be transient and are retried by a resumed run.
+func (r *record) settled() bool {
+	return !r.failed() || r.Outcome == rejectedOutcome
+}
+
+// channel returns the channel the input ended up in, for records restored
+// from a checkpoint the channel of byID with its ID. It returns nil if the
+// ID is used by more than one channel.
+func (r *record) channel(byID map[string]emm.Channels) *emm.Channel {
+	if r.ch != nil {
+		return r.ch
+	}
+	cs := byID[r.Channel]
+	if len(cs) > 1 {
+		log.Printf("Channel ID %s of %s is used by %d channels", r.Channel, r.URL, len(cs))
+		return nil
+	}
+	if len(cs) == 0 {
+		return nil
+	}
+	return cs[0]
+}
+
+// channelsByID returns the channels of d by ID.
+func channelsByID(d *emm.Directory) map[string]emm.Channels {
+	byID := make(map[string]emm.Channels)
+	for _, c := range d.Channels {
+		byID[c.ID] = append(byID[c.ID], c)
+	}
+	return byID
+}
+
+// failed reports whether the input did not end up in the directory.
+func (r *record) failed() bool {
+	switch r.Outcome {
+	case emm.InsertNew, emm.InsertMerged, emm.InsertDuplicate:
+		return false
+	}
+	return true
+}
+
+// A summary counts the inputs of a run by outcome.
+type summary struct {
+	Inputs   int            `json:"inputs"`
+	Failed   int            `json:"failed"`
+	Outcomes map[string]int `json:"outcomes"`
+	TotalMs  int64          `json:"totalMs"`
+}
+
+// A report collects the records of a run.
+type report struct {
+	sync.Mutex
+	start   time.Time
+	records []*record
+}
+
+func newReport() *report {
+	return &report{start: time.Now(), records: []*record{}}
+}
+
+func (rep *report) add(r *record) {
+	rep.Lock()
+	defer rep.Unlock()
+	rep.records = append(rep.records, r)
+}
+
+// snapshot returns a copy of the records so far.
+func (rep *report) snapshot() []*record {
+	rep.Lock()
+	defer rep.Unlock()
+	return append([]*record{}, rep.records...)
+}
+
+// restore adds the records of the inputs settled before a resumed run. The
+// others are read and processed again.
+func (rep *report) restore(records []*record) {
+	rep.Lock()
+	defer rep.Unlock()
+	for _, r := range records {
+		if r.settled() {
+			rep.records = append(rep.records, r)
+		}
+	}
+}
+
+// journal sets the input lines of the channels added or merged in run as
+// their journaled source and marks the channels of all inputs fetched and
+// parsed as checked.
+func (rep *report) journal(run *emm.JournalRun, d *emm.Directory) {
+	rep.Lock()
+	defer rep.Unlock()
+	byID := channelsByID(d)
+	for _, r := range rep.records {
+		if r.failed() {
+			continue
+		}
+		c := r.channel(byID)
+		if c == nil {
+			continue
+		}
+		run.Checked = append(run.Checked, c)
+		if r.Outcome == emm.InsertDuplicate {
+			continue
+		}
+		s := fmt.Sprintf("%s: %s", run.Source, r.URL)
+		if r.Line > 0 {
+			s = fmt.Sprintf("%s line %d: %s", run.Source, r.Line, r.URL)
+		}
+		if prev, ok := run.Sources[c]; ok {
+			s = prev + "; " + s
+		}
+		run.Sources[c] = s
+	}
+}
+
+func (rep *report) summary() summary {
+	rep.Lock()
+	defer rep.Unlock()
+	s := summary{
+		Inputs:   len(rep.records),
+		Outcomes: make(map[string]int),
+		TotalMs:  millis(time.Since(rep.start)),
+	}
+	for _, r := range rep.records {
+		s.Outcomes[r.Outcome]++
+		if r.failed() {
+			s.Failed++
+		}
+	}
+	return s
+}
+
+func (s summary) String() string {
+	var outcomes []string
+	for o := range s.Outcomes {
+		outcomes = append(outcomes, o)
+	}
+	sort.Strings(outcomes)
+	str := fmt.Sprintf("%d inputs, %d failed", s.Inputs, s.Failed)
+	for _, o := range outcomes {
+		str += fmt.Sprintf(", %d %s", s.Outcomes[o], o)
+	}
+	return str
+}
+
+// write writes the records, ordered by line, and the summary to w as one
+// JSON object or, with lines, as JSON lines with the summary last.
+func (rep *report) write(w io.Writer, lines bool) error {
+	s := rep.summary()
+	rep.Lock()
+	defer rep.Unlock()
+	sort.SliceStable(rep.records, func(i, j int) bool {
+		return rep.records[i].Line < rep.records[j].Line
+	})
+	if !lines {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Records []*record `json:"records"`
+			Summary summary   `json:"summary"`
+		}{rep.records, s})
+	}
+	enc := json.NewEncoder(w)
+	for _, r := range rep.records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(struct {
+		Summary summary `json:"summary"`
+	}{s})
+}
+
+// writeText writes a readable summary of the records, ordered by line, to
+// w: the fields of every new channel of d, the channel inputs were merged
+// into or duplicate of, and why the others failed.
+func (rep *report) writeText(w io.Writer, d *emm.Directory) error {
+	byID := channelsByID(d)
+	rs := rep.snapshot()
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Line < rs[j].Line
+	})
+	for _, r := range rs {
+		var err error
+		var c *emm.Channel
+		if r.Outcome == emm.InsertNew {
+			c = r.channel(byID)
+		}
+		switch {
+		case r.Outcome == emm.InsertNew && c != nil:
+			_, err = fmt.Fprintf(w, "new %s from %s\n", c.ID, r.URL)
+			for _, f := range emm.Fields {
+				if v, _ := c.Get(f); v != "" && f != "id" && (f != "disabled" || c.Disabled) {
+					fmt.Fprintf(w, "  %s: %s\n", f, v)
+				}
+			}
+		case r.Outcome == emm.InsertMerged:
+			_, err = fmt.Fprintf(w, "merged %s into %s\n", r.URL, r.Channel)
+		case r.Outcome == emm.InsertDuplicate:
+			_, err = fmt.Fprintf(w, "duplicate %s of %s\n", r.URL, r.Channel)
+		default:
+			_, err = fmt.Fprintf(w, "%s %s: %s\n", r.Outcome, r.URL, r.Error)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func millis(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// readTable sends the inputs parsed from the rows of a CSV or TSV table to
+// out until ctx is done. The header row names the columns: url, which is required, and any
+// field accepted on an input line, such as country, ranking, id or profile.
+// Empty cells are not set, lines starting with # are comments. The
+// diagnostic of every row, blank line and comment is recorded in ds.
+func readTable(ctx context.Context, r io.Reader, comma rune, ps emm.Profiles, base, def *emm.Profile, out chan<- *input, ds *diagnostics) error {
+	cf := &commentFilter{r: bufio.NewReader(r)}
+	cr := csv.NewReader(cf)
+	cr.Comma = comma
+	cr.TrimLeadingSpace = true
+	if comma == '\t' {
+		cr.LazyQuotes = true
+	}
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("Could not read header: %s", err)
+	}
+	urlCol := -1
+	for i, h := range header {
+		header[i] = strings.TrimSpace(h)
+		if header[i] == "url" {
+			urlCol = i
+		}
+	}
+	if urlCol == -1 {
+		return fmt.Errorf("No url column in header %q", header)
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			cf.record(0, 0, ds)
+			return nil
+		}
+		if pe, ok := err.(*csv.ParseError); ok {
+			cf.record(pe.StartLine, pe.StartLine, ds)
+			ds.record(diagnostic{Line: pe.StartLine, Raw: strings.Join(row, string(comma)), Status: rejected, Reason: pe.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		end := line
+		for _, v := range row {
+			end += strings.Count(v, "\n")
+		}
+		cf.record(line, end, ds)
+		d := diagnostic{Line: line, Raw: strings.Join(row, string(comma))}
+		in, err := parseRow(header, row, urlCol, ps, base, def)
+		switch {
+		case err != nil:
+			d.Status, d.Reason = rejected, err.Error()
+		case in == nil:
+			d.Status, d.Reason = ignored, "no url"
+		default:
+			d.Status, d.URL = accepted, in.url
+			if in.url != strings.TrimSpace(row[urlCol]) {
+				d.Status = normalized
+			}
+		}
+		ds.record(d)
+		if in != nil {
+			in.line = line
+			if err := send(ctx, out, in); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseRow parses a table row. Rows without URL are ignored.
+func parseRow(header, row []string, urlCol int, ps emm.Profiles, base, def *emm.Profile) (*input, error) {
+	u := strings.TrimSpace(row[urlCol])
+	if u == "" {
+		return nil, nil
+	}
+	u, _, err := emm.NormalizeURL(u)
+	if err != nil {
+		return nil, err
+	}
+	in := &input{url: u, profile: def, override: &emm.Profile{}}
+	for i, v := range row {
+		v = strings.TrimSpace(v)
+		if i == urlCol || v == "" {
+			continue
+		}
+		if err := in.set(header[i], v, ps, base); err != nil {
+			return nil, fmt.Errorf("Column %s: %s", header[i], err)
+		}
+	}
+	return in, nil
+}
+
+// A commentFilter blanks the comment lines of a table, starting with #,
+// before they reach the CSV reader, so that quotes in comments do not
+// matter and line numbers are kept. The comments and blank lines, which the
+// CSV reader skips, are kept as diagnostics.
+type commentFilter struct {
+	r       *bufio.Reader
+	n       int
+	buf     []byte
+	skipped []diagnostic
+}
+
+func (f *commentFilter) Read(p []byte) (int, error) {
+	for len(f.buf) == 0 {
+		line, err := f.r.ReadString('\n')
+		if line == "" {
+			return 0, err
+		}
+		f.n++
+		raw := strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(raw), "#"):
+			f.skipped = append(f.skipped, diagnostic{Line: f.n, Raw: raw, Status: ignored, Reason: "comment"})
+			line = line[len(raw):]
+		case raw == "":
+			f.skipped = append(f.skipped, diagnostic{Line: f.n, Status: ignored, Reason: "blank line"})
+		}
+		f.buf = []byte(line)
+	}
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	return n, nil
+}
+
+// record records the diagnostics of the lines skipped before the row from
+// line to end, or of all lines skipped if line is 0, in ds. Lines within
+// the row belong to its quoted fields and are dropped.
+func (f *commentFilter) record(line, end int, ds *diagnostics) {
+	for len(f.skipped) > 0 && (line == 0 || f.skipped[0].Line <= end) {
+		if line == 0 || f.skipped[0].Line < line {
+			ds.record(f.skipped[0])
+		}
+		f.skipped = f.skipped[1:]
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// timeLayouts are the accepted layouts of -to, in local time unless a zone
+// is given.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	for _, l := range timeLayouts {
+		if t, err := time.ParseInLocation(l, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid time %q, want e.g. 2006-01-02T15:04:05", s)
+}
+
+// runUndo reverts journaled changes: those of the last N runs, those of a
+// run or all since a point in time.
+func runUndo(args []string) error {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	dir := addDirFlags(fs).writable(fs)
+	dryRun := fs.Bool("dry-run", false, "Write the changes as unified diff instead of the directory")
+	last := fs.Int("last", 0, "Undo the changes of the last N journaled runs")
+	run := fs.String("run", "", "Undo the changes of the run with this ID")
+	to := fs.String("to", "", "Restore the directory as it was at this time")
+	fs.Parse(args)
+
+	given := 0
+	for _, g := range []bool{*last > 0, *run != "", *to != ""} {
+		if g {
+			given++
+		}
+	}
+	if given != 1 {
+		fs.Usage()
+		return fmt.Errorf("undo needs one of -last, -run or -to")
+	}
+	var since time.Time
+	if *to != "" {
+		t, err := parseTime(*to)
+		if err != nil {
+			return err
+		}
+		since = t
+	}
+
+	d, _, err := dir.load()
+	if err != nil {
+		return err
+	}
+	defer dir.close()
+	es, err := emm.ReadJournal(dir.journal())
+	if err != nil {
+		return err
+	}
+	var undo []*emm.JournalEntry
+	switch {
+	case *last > 0:
+		runs := make(map[string]bool)
+		for i := len(es) - 1; i >= 0; i-- {
+			if !runs[es[i].Run] && len(runs) == *last {
+				break
+			}
+			runs[es[i].Run] = true
+			undo = es[i:]
+		}
+		if len(runs) < *last {
+			return fmt.Errorf("Journal has only %d runs", len(runs))
+		}
+	case *run != "":
+		for _, e := range es {
+			if e.Run == *run {
+				undo = append(undo, e)
+			}
+		}
+		if undo == nil {
+			return fmt.Errorf("No changes of run %s in the journal", *run)
+		}
+	default:
+		for i, e := range es {
+			if e.Time.After(since) {
+				undo = es[i:]
+				break
+			}
+		}
+	}
+
+	before := d.Clone()
+	if err := d.Revert(undo); err != nil {
+		return err
+	}
+	log.Printf("undo: reverted %d journaled changes", len(undo))
+	if *dryRun {
+		return emm.WriteUnified(os.Stdout, "before", "after", emm.Diff(before, d))
+	}
+	return dir.save(d)
+}
+
+// runJournal writes the journaled changes of a directory.
+func runJournal(args []string) error {
+	fs := flag.NewFlagSet("journal", flag.ExitOnError)
+	dir := addDirFlags(fs)
+	format := fs.String("f", "table", "Output format: table or jsonl")
+	run := fs.String("run", "", "Only the changes of the run with this ID")
+	fs.Parse(args)
+
+	if _, _, err := dir.load(); err != nil {
+		return err
+	}
+	es, err := emm.ReadJournal(dir.journal())
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range es {
+			if *run == "" || e.Run == *run {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "time\trun\toperator\tcommand\top\tid\tchanges\tsource")
+		for _, e := range es {
+			if *run != "" && e.Run != *run {
+				continue
+			}
+			var changes []string
+			for _, f := range e.Fields {
+				changes = append(changes, fmt.Sprintf("%s=%s", f.Field, f.New))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", formatTime(&e.Time),
+				e.Run, e.Operator, e.Command, e.Op, e.ID, strings.Join(changes, " "), e.Source)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("Unknown format %q", *format)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/certeu/emmchan/emm"
+)
+
+// runValidate checks a channel directory and writes the findings to
+// STDOUT. It fails if any finding is an error.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	df := addDirFlags(fs)
+	format := fs.String("f", "json", "Output format: json or text")
+	fs.Parse(args)
+
+	d, _, err := df.load()
+	if err != nil {
+		return err
+	}
+	findings := d.Validate()
+
+	switch *format {
+	case "json":
+		if findings == nil {
+			findings = emm.Findings{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return err
+		}
+	case "text":
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+	default:
+		return fmt.Errorf("Unknown output format %q", *format)
+	}
+
+	if n := findings.Errors(); n > 0 {
+		return fmt.Errorf("%d errors in %d channels", n, len(d.Channels))
+	}
+	return nil
+}
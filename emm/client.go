@@ -1,38 +1,46 @@
 package emm
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"time"
 )
 
-const (
-	userAgent = "Mozilla/4.0 (compatible; MSIE 7.0; Windows NT 6.3; Trident/7.0; .NET4.0E; .NET4.0C)"
-	timeout   = 30 * time.Second
-)
+const userAgent = "Mozilla/4.0 (compatible; MSIE 7.0; Windows NT 6.3; Trident/7.0; .NET4.0E; .NET4.0C)"
+
+// DefaultTimeout is the per-request timeout of a new Client.
+const DefaultTimeout = 30 * time.Second
 
 // A Client is used to fetch new feeds.
 type Client struct {
 	httpClient *http.Client
 	UserAgent  string
+	// Timeout limits each request, from sending it to closing the
+	// response body. Zero means no limit besides that of the context.
+	Timeout time.Duration
 }
 
-// NewClient returns a new EMM client.
+// NewClient returns a new EMM client using httpClient, or
+// http.DefaultClient if nil. httpClient is not modified.
 func NewClient(httpClient *http.Client) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
-	httpClient.Timeout = timeout
 
 	c := &Client{
 		httpClient: httpClient,
 		UserAgent:  userAgent,
+		Timeout:    DefaultTimeout,
 	}
 
 	return c
 }
 
-// Get fetches a URL and returns the HTTP response.
-func (c *Client) Get(url string) (*http.Response, error) {
+// Get fetches a URL and returns the HTTP response. The request is canceled
+// when ctx is done or the client timeout expires, whichever comes first;
+// the response body must be closed.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -41,5 +49,41 @@ func (c *Client) Get(url string) (*http.Response, error) {
 		req.Header.Set("User-Agent", c.UserAgent)
 	}
 
-	return c.httpClient.Do(req)
+	cancel := func() {}
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{resp.Body, cancel}
+	return resp, nil
+}
+
+// cancelBody releases the context of a request when the response body is
+// closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Redirects returns the URLs redirected from to get response resp, in the
+// order they were requested.
+func Redirects(resp *http.Response) []string {
+	var urls []string
+	if resp.Request == nil {
+		return urls
+	}
+	for r := resp.Request.Response; r != nil && r.Request != nil; r = r.Request.Response {
+		urls = append([]string{r.Request.URL.String()}, urls...)
+	}
+	return urls
 }
@@ -2,11 +2,13 @@
 package emm
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 var (
@@ -44,7 +46,7 @@ func TestGet(t *testing.T) {
 		}
 		fmt.Fprint(w, `response body`)
 	})
-	resp, err := client.Get(fmt.Sprintf("%s/feed", server.URL))
+	resp, err := client.Get(context.Background(), fmt.Sprintf("%s/feed", server.URL))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -57,3 +59,59 @@ func TestGet(t *testing.T) {
 		t.Errorf("Response body = %v; want response body", string(actual))
 	}
 }
+
+func TestRedirects(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusFound))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `response body`)
+	})
+	resp, err := client.Get(context.Background(), fmt.Sprintf("%s/old", server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	got := Redirects(resp)
+	if len(got) != 2 || got[0] != server.URL+"/old" || got[1] != server.URL+"/moved" {
+		t.Errorf("Redirects() = %v; want /old, /moved", got)
+	}
+	if resp.Request.URL.Path != "/feed" {
+		t.Errorf("Final URL = %s; want /feed", resp.Request.URL)
+	}
+}
+
+func TestGetCanceled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	client.Timeout = 50 * time.Millisecond
+	if _, err := client.Get(context.Background(), server.URL+"/slow"); err == nil {
+		t.Errorf("Get() past the client timeout returned no error")
+	}
+
+	client.Timeout = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Get(ctx, server.URL+"/slow"); err == nil {
+		t.Errorf("Get() past the context deadline returned no error")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	hc := &http.Client{}
+	if c := NewClient(hc); hc.Timeout != 0 || c.Timeout != DefaultTimeout {
+		t.Errorf("NewClient() set the http.Client timeout to %s", hc.Timeout)
+	}
+	if NewClient(nil); http.DefaultClient.Timeout != 0 {
+		t.Errorf("NewClient(nil) set the http.DefaultClient timeout to %s", http.DefaultClient.Timeout)
+	}
+}
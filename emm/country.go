@@ -0,0 +1,231 @@
+package emm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Weights of the signals used for country inference.
+const (
+	weightOverride = 1.0
+	weightTLD      = 0.6
+	weightLanguage = 0.5
+	weightOGLocale = 0.4
+	weightHTMLLang = 0.3
+)
+
+// ReviewThreshold is the confidence below which an inferred value should be
+// reviewed.
+const ReviewThreshold = 0.5
+
+// A Signal is a single hint at the country of a channel.
+type Signal struct {
+	// Source names where the hint came from, e.g. "tld" or "og:locale".
+	Source string  `json:"source"`
+	Value  string  `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+// An Inference is an inferred channel field value.
+type Inference struct {
+	Value string `json:"value"`
+	// Source tells how the value was obtained, e.g. LanguageDetected.
+	Source string `json:"source,omitempty"`
+	// Confidence ranges from 0 (nothing known) to 1 (certain).
+	Confidence float64  `json:"confidence"`
+	Signals    []Signal `json:"signals,omitempty"`
+}
+
+// NeedsReview reports whether the inferred value was found with low
+// confidence.
+func (i *Inference) NeedsReview() bool {
+	return i.Value != "" && i.Confidence < ReviewThreshold
+}
+
+// A CountryInferrer infers the country and region of channels.
+type CountryInferrer struct {
+	// Overrides maps domains to country codes.
+	Overrides map[string]string `json:"countries"`
+	// Regions maps country codes to EMM regions. Countries missing here are
+	// looked up in DefaultRegions.
+	Regions map[string]string `json:"regions"`
+}
+
+// LoadCountryInferrer reads a country inferrer from a JSON object with the
+// optional members "countries", mapping domains to country codes, and
+// "regions", mapping country codes to regions.
+func LoadCountryInferrer(r io.Reader) (*CountryInferrer, error) {
+	ci := &CountryInferrer{}
+	if err := json.NewDecoder(r).Decode(ci); err != nil {
+		return nil, fmt.Errorf("Could not load country table: %s", err)
+	}
+	return ci, nil
+}
+
+// CountryInferrerFromFile loads a country inferrer from a JSON file.
+func CountryInferrerFromFile(path string) (*CountryInferrer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadCountryInferrer(f)
+}
+
+// Infer infers the country and region of channel c. homepage holds the
+// HTML of the channel homepage and may be nil.
+func (ci *CountryInferrer) Infer(c *Channel, homepage []byte) (country, region *Inference) {
+	var signals []Signal
+	domain := c.Domain()
+	if cc, ok := ci.Overrides[domain]; ok {
+		signals = append(signals, Signal{"override", strings.ToUpper(cc), weightOverride})
+	}
+	if cc := tldCountry(domain); cc != "" {
+		signals = append(signals, Signal{"tld", cc, weightTLD})
+	}
+	if cc := tagCountry(c.languageTag()); cc != "" {
+		signals = append(signals, Signal{"language", cc, weightLanguage})
+	}
+	ogLocale, htmlLang := homepageLocales(homepage)
+	if cc := tagCountry(ogLocale); cc != "" {
+		signals = append(signals, Signal{"og:locale", cc, weightOGLocale})
+	}
+	if cc := tagCountry(htmlLang); cc != "" {
+		signals = append(signals, Signal{"html lang", cc, weightHTMLLang})
+	}
+
+	country = combine(signals)
+	region = &Inference{}
+	if r := ci.Region(country.Value); r != "" {
+		region.Value = r
+		region.Confidence = country.Confidence
+		region.Signals = []Signal{{"country", country.Value, country.Confidence}}
+	}
+	return country, region
+}
+
+// Region returns the region of the country with code cc.
+func (ci *CountryInferrer) Region(cc string) string {
+	if r, ok := ci.Regions[cc]; ok {
+		return r
+	}
+	return DefaultRegions[cc]
+}
+
+// combine picks the country with the highest total signal weight. The
+// confidence is the weight of the winner, capped at 1, scaled by its share
+// of the total weight.
+func combine(signals []Signal) *Inference {
+	inf := &Inference{Signals: signals}
+	scores := make(map[string]float64)
+	var total float64
+	for _, s := range signals {
+		scores[s.Value] += s.Weight
+		total += s.Weight
+	}
+	var best float64
+	var countries []string
+	for cc := range scores {
+		countries = append(countries, cc)
+	}
+	sort.Strings(countries)
+	for _, cc := range countries {
+		if scores[cc] > best {
+			best = scores[cc]
+			inf.Value = cc
+		}
+	}
+	if total > 0 {
+		inf.Confidence = math.Min(best, 1) * best / total
+	}
+	return inf
+}
+
+// vanityTLDs are country code TLDs marketed and mostly used as generic
+// TLDs, which say nothing about the country of a site.
+var vanityTLDs = map[string]bool{
+	"ai": true, "cc": true, "co": true, "fm": true, "gg": true, "io": true,
+	"ly": true, "me": true, "nu": true, "to": true, "tv": true, "ws": true,
+}
+
+// tldCountry returns the country code of the top level domain of domain, or
+// "" if it is not a country code TLD or a vanity one.
+func tldCountry(domain string) string {
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	if len(tld) != 2 || vanityTLDs[tld] {
+		return ""
+	}
+	if tld == "uk" {
+		return "GB"
+	}
+	r, err := language.ParseRegion(tld)
+	if err != nil || !r.IsCountry() {
+		return ""
+	}
+	return r.String()
+}
+
+// tagCountry returns the country given explicitly in a language tag or
+// locale, e.g. "BE" for "fr-BE" or "fr_BE.UTF-8".
+// languageTag returns the language tag the feed of the channel declares,
+// which unlike the channel language keeps its region, or else the channel
+// language.
+func (e *Channel) languageTag() string {
+	if e.Feed != nil && e.Feed.Channel != nil && e.Feed.Channel.Language != "" {
+		return e.Feed.Channel.Language
+	}
+	return e.Language
+}
+
+func tagCountry(tag string) string {
+	if i := strings.IndexAny(tag, ".@"); i != -1 {
+		tag = tag[:i]
+	}
+	t, err := language.Parse(strings.Replace(tag, "_", "-", -1))
+	if err != nil {
+		return ""
+	}
+	r, conf := t.Region()
+	if conf != language.Exact || !r.IsCountry() {
+		return ""
+	}
+	return r.String()
+}
+
+var (
+	htmlTag  = regexp.MustCompile(`(?i)<html\s[^>]*>`)
+	metaTag  = regexp.MustCompile(`(?i)<meta\s[^>]*>`)
+	htmlAttr = regexp.MustCompile(`(?i)([a-z:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// homepageLocales extracts the og:locale and html lang values from a HTML
+// page.
+func homepageLocales(page []byte) (ogLocale, htmlLang string) {
+	if t := htmlTag.Find(page); t != nil {
+		htmlLang = attrs(t)["lang"]
+	}
+	for _, t := range metaTag.FindAll(page, -1) {
+		a := attrs(t)
+		if strings.ToLower(a["property"]) == "og:locale" {
+			ogLocale = a["content"]
+			break
+		}
+	}
+	return ogLocale, htmlLang
+}
+
+func attrs(tag []byte) map[string]string {
+	a := make(map[string]string)
+	for _, m := range htmlAttr.FindAllSubmatch(tag, -1) {
+		a[strings.ToLower(string(m[1]))] = string(m[2]) + string(m[3]) + string(m[4])
+	}
+	return a
+}
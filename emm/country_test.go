@@ -0,0 +1,88 @@
+package emm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/certeu/emmchan/rss"
+)
+
+const homepage = `<!DOCTYPE html>
+<html class="no-js" lang="fr-BE">
+<head>
+<meta charset="utf-8">
+<meta content="fr_BE" property="og:locale" />
+</head>`
+
+func TestInferCountry(t *testing.T) {
+	ci, err := LoadCountryInferrer(strings.NewReader(`{
+		"countries": {"example.org": "de"},
+		"regions": {"BE": "Benelux"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		identifier string
+		language   string
+		page       string
+		country    string
+		region     string
+		review     bool
+	}{
+		{"https://www.lesoir.be/", "fr", homepage, "BE", "Benelux", false},
+		{"https://www.lemonde.fr/", "fr-FR", "", "FR", "Europe", false},
+		{"https://example.org/", "en", "", "DE", "Europe", false},
+		{"https://www.bbc.co.uk/", "en-gb", "", "GB", "Europe", false},
+		{"https://www.example.com/", "en", homepage, "BE", "Benelux", false},
+		{"https://www.example.com/", "en", `<html lang="nl-NL">`, "NL", "Europe", true},
+		{"https://europa.eu/", "en", "", "", "", false},
+		{"https://www.lesoir.be/", "fr-FR", "", "BE", "Benelux", true},
+		{"https://blog.example.io/", "en", "", "", "", false},
+		{"https://www.example.tv/", "en", homepage, "BE", "Benelux", false},
+	}
+	for _, test := range tests {
+		c := &Channel{Identifier: test.identifier, Language: test.language}
+		country, region := ci.Infer(c, []byte(test.page))
+		if country.Value != test.country || region.Value != test.region {
+			t.Errorf("Infer(%s) = %s, %s; want %s, %s", test.identifier,
+				country.Value, region.Value, test.country, test.region)
+		}
+		if country.NeedsReview() != test.review {
+			t.Errorf("Infer(%s).NeedsReview() = %v (%.2f); want %v", test.identifier,
+				country.NeedsReview(), country.Confidence, test.review)
+		}
+	}
+}
+
+func TestInferCountryFeed(t *testing.T) {
+	f := &rss.Feed{Channel: &rss.Channel{
+		URL:      "https://www.example.com/feed/",
+		Link:     "https://www.example.com/",
+		Title:    "Example",
+		Language: "fr-BE",
+	}}
+	c := NewChannel(f, "Public")
+	if c.Language != "fr" {
+		t.Fatalf("NewChannel() language = %q; want fr", c.Language)
+	}
+	country, _ := (&CountryInferrer{}).Infer(c, nil)
+	if country.Value != "BE" {
+		t.Errorf("Infer() of a feed declaring fr-BE = %q; want BE", country.Value)
+	}
+}
+
+func TestTagCountry(t *testing.T) {
+	tests := map[string]string{
+		"fr-BE":       "BE",
+		"fr_BE.UTF-8": "BE",
+		"en":          "",
+		"es-419":      "",
+		"English":     "",
+	}
+	for in, want := range tests {
+		if cc := tagCountry(in); cc != want {
+			t.Errorf("tagCountry(%q) = %q; want %q", in, cc, want)
+		}
+	}
+}
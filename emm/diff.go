@@ -0,0 +1,271 @@
+package emm
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Channel diff statuses.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// A FieldChange is a changed channel field.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// A ChannelDiff describes how a channel differs between two directories.
+type ChannelDiff struct {
+	Status string `json:"status"`
+	// ID of the channel in the new directory, or the old one if removed
+	ID         string `json:"id"`
+	Identifier string `json:"identifier"`
+	// Old and New are the channel in both directories, nil if missing
+	Old          *Channel      `json:"-"`
+	New          *Channel      `json:"-"`
+	Fields       []FieldChange `json:"fields,omitempty"`
+	FeedsAdded   []string      `json:"feedsAdded,omitempty"`
+	FeedsRemoved []string      `json:"feedsRemoved,omitempty"`
+}
+
+// Diff compares two directories. Channels are matched by ID and then by
+// identifier. The result lists the removed and modified channels in the
+// order of the old directory, followed by the added channels in the order
+// of the new directory.
+func Diff(old, new *Directory) []*ChannelDiff {
+	return diff(old, new, sameID, sameIdentifier)
+}
+
+// Track compares two directories like Diff, but matches channels by
+// identifier and feeds before ID, so that channels are followed across
+// changes of their ID, such as migrations that swap or shift IDs. It is
+// used for the journal and provenance.
+func Track(old, new *Directory) []*ChannelDiff {
+	return diff(old, new, func(o, n *Channel) bool {
+		return sameIdentifier(o, n) && sameID(o, n)
+	}, func(o, n *Channel) bool {
+		return sameIdentifier(o, n) && shareFeed(o, n)
+	}, shareFeed, sameIdentifier, sameID)
+}
+
+// diff compares two directories, matching channels by the first rule any
+// unmatched pair of channels satisfies.
+func diff(old, new *Directory, rules ...func(o, n *Channel) bool) []*ChannelDiff {
+	match := make(map[*Channel]*Channel)
+	matched := make(map[*Channel]bool)
+	for _, rule := range rules {
+		for _, oc := range old.Channels {
+			if match[oc] != nil {
+				continue
+			}
+			for _, nc := range new.Channels {
+				if !matched[nc] && rule(oc, nc) {
+					match[oc], matched[nc] = nc, true
+					break
+				}
+			}
+		}
+	}
+	var diffs []*ChannelDiff
+	for _, oc := range old.Channels {
+		nc := match[oc]
+		if nc == nil {
+			diffs = append(diffs, &ChannelDiff{Status: Removed, ID: oc.ID, Identifier: oc.Identifier, Old: oc})
+			continue
+		}
+		if cd := DiffChannel(oc, nc); cd != nil {
+			diffs = append(diffs, cd)
+		}
+	}
+	for _, nc := range new.Channels {
+		if !matched[nc] {
+			diffs = append(diffs, &ChannelDiff{Status: Added, ID: nc.ID, Identifier: nc.Identifier, New: nc})
+		}
+	}
+	return diffs
+}
+
+// match returns the first channel not in matched with the ID of c or, if
+// there is none, with the identifier of c.
+func (cs Channels) match(c *Channel, matched map[*Channel]bool) *Channel {
+	for _, o := range cs {
+		if !matched[o] && o.ID == c.ID {
+			return o
+		}
+	}
+	for _, o := range cs {
+		if !matched[o] && o.Identifier == c.Identifier {
+			return o
+		}
+	}
+	return nil
+}
+
+func sameID(o, n *Channel) bool {
+	return o.ID == n.ID
+}
+
+func sameIdentifier(o, n *Channel) bool {
+	return o.Identifier == n.Identifier
+}
+
+// shareFeed reports whether the channels have a feed in common.
+func shareFeed(o, n *Channel) bool {
+	urls := n.feedURLs()
+	for _, u := range o.feedURLs() {
+		if contains(urls, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffChannel compares the fields and feeds of two versions of a channel. It
+// returns nil if they are equal.
+func DiffChannel(old, new *Channel) *ChannelDiff {
+	cd := &ChannelDiff{Status: Modified, ID: new.ID, Identifier: new.Identifier, Old: old, New: new}
+	for _, f := range Fields {
+		ov, _ := old.Get(f)
+		nv, _ := new.Get(f)
+		if ov != nv {
+			cd.Fields = append(cd.Fields, FieldChange{f, ov, nv})
+		}
+	}
+	oldFeeds, newFeeds := old.feedURLs(), new.feedURLs()
+	for _, u := range newFeeds {
+		if !contains(oldFeeds, u) {
+			cd.FeedsAdded = append(cd.FeedsAdded, u)
+		}
+	}
+	for _, u := range oldFeeds {
+		if !contains(newFeeds, u) {
+			cd.FeedsRemoved = append(cd.FeedsRemoved, u)
+		}
+	}
+	if cd.Fields == nil && cd.FeedsAdded == nil && cd.FeedsRemoved == nil {
+		return nil
+	}
+	return cd
+}
+
+// feedURLs returns the URLs of the channel feeds.
+func (e *Channel) feedURLs() []string {
+	if e.Feeds == nil {
+		return nil
+	}
+	var urls []string
+	for _, f := range *e.Feeds {
+		urls = append(urls, feedKey(f))
+	}
+	return urls
+}
+
+// feedKey returns the URL of a feed as string.
+func feedKey(f Feed) string {
+	u := url.URL(f.URL)
+	return u.String()
+}
+
+// lines returns the channel as "field: value" lines, followed by one line
+// per feed.
+func (e *Channel) lines() []string {
+	var lines []string
+	for _, f := range Fields {
+		v, _ := e.Get(f)
+		lines = append(lines, fmt.Sprintf("%s: %s", f, v))
+	}
+	if e.Feeds != nil {
+		for _, f := range *e.Feeds {
+			lines = append(lines, fmt.Sprintf("feed: %s %s", feedKey(f), f.Title))
+		}
+	}
+	return lines
+}
+
+// WriteText writes a readable summary of directory differences to w.
+func WriteText(w io.Writer, diffs []*ChannelDiff) error {
+	for _, cd := range diffs {
+		if _, err := fmt.Fprintf(w, "%s %s (%s)\n", cd.Status, cd.ID, cd.Identifier); err != nil {
+			return err
+		}
+		for _, f := range cd.Fields {
+			fmt.Fprintf(w, "  %s: %q -> %q\n", f.Field, f.Old, f.New)
+		}
+		for _, u := range cd.FeedsAdded {
+			fmt.Fprintf(w, "  feed added: %s\n", u)
+		}
+		for _, u := range cd.FeedsRemoved {
+			fmt.Fprintf(w, "  feed removed: %s\n", u)
+		}
+	}
+	return nil
+}
+
+// WriteUnified writes directory differences to w in unified diff style,
+// with one hunk per channel. oldName and newName label the directories.
+func WriteUnified(w io.Writer, oldName, newName string, diffs []*ChannelDiff) error {
+	if len(diffs) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName); err != nil {
+		return err
+	}
+	for _, cd := range diffs {
+		var ol, nl []string
+		if cd.Old != nil {
+			ol = cd.Old.lines()
+		}
+		if cd.New != nil {
+			nl = cd.New.lines()
+		}
+		fmt.Fprintf(w, "@@ %s %s @@\n", cd.Status, cd.ID)
+		for _, l := range unified(ol, nl) {
+			fmt.Fprintln(w, l)
+		}
+	}
+	return nil
+}
+
+// unified returns the lines of a line diff of a and b, prefixed with " ",
+// "-" or "+".
+func unified(a, b []string) []string {
+	// longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, " "+a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "-"+a[i])
+			i++
+		default:
+			out = append(out, "+"+b[j])
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package emm
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := newDirectory(cd)
+	old.Add(&Channel{ID: "gone", Identifier: "http://gone.example/"})
+	new := newDirectory(cd)
+	new.Channels[0].ID = "malekal"
+	new.Channels[0].CountryCode = "FR"
+	*new.Channels[0].Feeds = append(*new.Channels[0].Feeds, Feed{Title: "news", URL: feedURL("http://www.malekal.com/news/feed/")})
+	added := NewChannel(rssFeed, "Public")
+	new.Add(added)
+
+	diffs := Diff(old, new)
+	if len(diffs) != 3 {
+		t.Fatalf("Diff() = %d diffs; want 3", len(diffs))
+	}
+	m := diffs[0]
+	if m.Status != Modified || m.ID != "malekal" {
+		t.Errorf("Diff()[0] = %s %s; want modified malekal", m.Status, m.ID)
+	}
+	want := []FieldChange{{"id", "P_malekalssite", "malekal"}, {"country", "US", "FR"}}
+	if len(m.Fields) != 2 || m.Fields[0] != want[0] || m.Fields[1] != want[1] {
+		t.Errorf("Diff()[0].Fields = %v; want %v", m.Fields, want)
+	}
+	if len(m.FeedsAdded) != 1 || m.FeedsAdded[0] != "http://www.malekal.com/news/feed/" {
+		t.Errorf("Diff()[0].FeedsAdded = %v", m.FeedsAdded)
+	}
+	if diffs[1].Status != Removed || diffs[1].ID != "gone" {
+		t.Errorf("Diff()[1] = %s %s; want removed gone", diffs[1].Status, diffs[1].ID)
+	}
+	if diffs[2].Status != Added || diffs[2].New != added {
+		t.Errorf("Diff()[2] = %s %s; want added %s", diffs[2].Status, diffs[2].ID, added.ID)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteUnified(&buf, "a.xml", "b.xml", diffs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{"--- a.xml", "-id: P_malekalssite", "+id: malekal", " type: webnews", "+feed: http://www.malekal.com/news/feed/ news"} {
+		if !strings.Contains(buf.String(), l+"\n") {
+			t.Errorf("WriteUnified() is missing line %q:\n%s", l, buf.String())
+		}
+	}
+	if len(Diff(old, old)) != 0 {
+		t.Errorf("Diff() of equal directories is not empty")
+	}
+}
+
+func TestTrack(t *testing.T) {
+	old := newDirectory(cd)
+	old.Add(NewChannel(rssFeed, "Public"))
+	old.Channels[1].ID = "Second"
+	new := old.Clone()
+	new.Channels[0].ID, new.Channels[1].ID = "Second", "P_malekalssite"
+
+	diffs := Track(old, new)
+	if len(diffs) != 2 || diffs[0].Old != old.Channels[0] || diffs[0].New != new.Channels[0] ||
+		diffs[0].Fields[0] != (FieldChange{"id", "P_malekalssite", "Second"}) {
+		t.Errorf("Track() of swapped IDs = %v; want the channels matched by identifier", diffs)
+	}
+	if diffs := Diff(old, new); len(diffs) != 2 || diffs[0].New != new.Channels[1] {
+		t.Errorf("Diff() of swapped IDs = %v; want the channels matched by ID", diffs)
+	}
+}
+
+func feedURL(s string) FeedURL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return FeedURL(*u)
+}
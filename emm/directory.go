@@ -7,7 +7,6 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
-	"regexp"
 	"strings"
 	"sync"
 
@@ -30,16 +29,46 @@ func (c *Channels) Index(id string) int {
 // Directory represents a channel directory tree.
 type Directory struct {
 	sync.Mutex
-	// Marks EMM instance
-	Instance string
+	// Names the EMM instance, see LookupInstance
+	Instance string   `xml:"-"`
 	XMLName  xml.Name `xml:"directory"`
 	Channels Channels `xml:"channel"`
 }
 
+// Outcomes of inserting a channel into a directory.
+const (
+	// InsertNew is a channel added as new channel.
+	InsertNew = "new"
+	// InsertMerged is a channel whose feeds were merged into the channel
+	// with the same identifier.
+	InsertMerged = "merged"
+	// InsertDuplicate is a channel whose feeds are all in the directory.
+	InsertDuplicate = "duplicate"
+)
+
 // Add appends an Channel to directory channel slice.
 func (d *Directory) Add(ec *Channel) {
 	d.Lock()
 	defer d.Unlock()
+	d.add(ec)
+}
+
+// Insert adds channel ec to the directory unless all its feeds are in the
+// directory already. The feeds of a channel with the identifier of an
+// existing channel are merged into that channel. Insert returns the outcome
+// and the channel of the directory holding the feeds.
+func (d *Directory) Insert(ec *Channel) (string, *Channel) {
+	d.Lock()
+	defer d.Unlock()
+	if dup := d.Channels.holding(ec.feedURLs()); dup != nil {
+		return InsertDuplicate, dup
+	}
+	return d.add(ec)
+}
+
+// add merges the feeds of ec into the channel with its identifier, or else
+// appends it.
+func (d *Directory) add(ec *Channel) (string, *Channel) {
 	idx := d.Channels.Index(ec.Identifier)
 	if idx != -1 {
 		feeds := d.Channels[idx].Feeds
@@ -51,9 +80,37 @@ func (d *Directory) Add(ec *Channel) {
 			}
 		}
 		d.Channels[idx].Feeds = feeds
-		return
+		return InsertMerged, d.Channels[idx]
 	}
 	d.Channels = append(d.Channels, ec)
+	return InsertNew, ec
+}
+
+// holding returns the first channel holding all feed URLs, or nil if there
+// is none or urls is empty.
+func (cs Channels) holding(urls []string) *Channel {
+	for _, u := range urls {
+		if u == "" {
+			return nil
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	for _, c := range cs {
+		have := c.feedURLs()
+		all := true
+		for _, u := range urls {
+			if !contains(have, u) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return c
+		}
+	}
+	return nil
 }
 
 // Load will load a channel directory tree from an io.Reader.
@@ -72,9 +129,16 @@ func (d *Directory) Load(ch io.Reader) error {
 	return nil
 }
 
-// Dump writes the channel directory to an io.Writer.
+// Dump writes the channel directory to an io.Writer. Disabled channels are
+// left out.
 func (d *Directory) Dump(ch io.Writer) error {
-	out, err := xml.MarshalIndent(d, "", "  ")
+	enabled := &Directory{XMLName: d.XMLName}
+	for _, c := range d.Channels {
+		if !c.Disabled {
+			enabled.Channels = append(enabled.Channels, c)
+		}
+	}
+	out, err := xml.MarshalIndent(enabled, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -89,8 +153,20 @@ func NewDirectory(xmlstr string) *Directory {
 	return d
 }
 
-// NewChannel creates a new EMM channel from a RSS feed.
+// NewChannel creates a new EMM channel from a RSS feed using the values of
+// DefaultProfile and the defaults of instance inst.
 func NewChannel(r *rss.Feed, inst string) *Channel {
+	if inst == "" {
+		inst = "Public"
+	}
+	return NewChannelProfile(r, inst, instanceDef(inst).Defaults(DefaultProfile))
+}
+
+// NewChannelProfile creates a new EMM channel from a RSS, Atom or RDF feed.
+// Fields not provided by the feed, such as its format, are taken from
+// profile p. The feed language is normalized, or detected if the feed
+// declares none.
+func NewChannelProfile(r *rss.Feed, inst string, p *Profile) *Channel {
 	if inst == "" {
 		inst = "Public"
 	}
@@ -103,23 +179,22 @@ func NewChannel(r *rss.Feed, inst string) *Channel {
 		Feed{rc.Title, FeedURL(*u)},
 	}
 	e := &Channel{
-		Feed:            r,
-		Format:          "rss",
-		Type:            "webnews",
-		Subject:         "eucert",
-		Description:     rc.Description,
-		Identifier:      rc.Link,
-		Encoding:        r.Encoding,
-		CountryCode:     "US",
-		Region:          "Global",
-		Category:        "Specialist",
-		Ranking:         1,
-		Language:        rc.Language,
-		UpdatePeriod:    "daily",
-		UpdateFrequency: 4,
-		Feeds:           &feeds,
-	}
-	e.genID(inst)
+		Feed:       r,
+		Identifier: rc.Link,
+		Feeds:      &feeds,
+	}
+	p.Apply(e)
+	lang := InferLanguage(r)
+	(&Profile{
+		Format:      r.Format,
+		Description: rc.Description,
+		Encoding:    r.Encoding,
+		Language:    lang.Value,
+	}).Apply(e)
+	if lang.Value != "" {
+		e.Inferred = map[string]*Inference{"language": lang}
+	}
+	e.ID = DefaultIDScheme.ID(e, inst)
 	e.setEncoding()
 	return e
 
@@ -128,35 +203,28 @@ func NewChannel(r *rss.Feed, inst string) *Channel {
 // Channel represents a channel entry.
 type Channel struct {
 	// the RSS feed from which this channel was generared
-	Feed            *rss.Feed `xml:"-"`
-	ID              string    `xml:"id,attr"`
-	Format          string    `xml:"format"`
-	Type            string    `xml:"type"`
-	Subject         string    `xml:"subject"`
-	Description     string    `xml:"description"`
-	Identifier      string    `xml:"identifier"`
-	Encoding        string    `xml:"encoding"`
-	CountryCode     string    `xml:"country"`
-	Region          string    `xml:"region"`
-	Category        string    `xml:"category"`
-	Ranking         int       `xml:"ranking"`
-	Language        string    `xml:"language"`
-	UpdatePeriod    string    `xml:"schedule>updatePeriod"`
-	UpdateFrequency int       `xml:"schedule>updateFrequency"`
-	Feeds           *Feeds    `xml:"feed"`
-}
-
-func (e *Channel) genID(inst string) {
-	t := e.Feed.Channel.Title
-	if t == "" {
-		t = e.Feed.Channel.URL
-	}
-	alph, _ := regexp.Compile("[^a-zA-Z0-9]*")
-	title := alph.ReplaceAllString(t, "")
-	if inst == "Private" {
-		title = fmt.Sprintf("P_%s", title)
-	}
-	e.ID = title
+	Feed *rss.Feed `xml:"-"`
+	// inferred field values by field name
+	Inferred map[string]*Inference `xml:"-"`
+	// Disabled channels are kept in the directory but left out of the EMM
+	// XML, the provenance store keeps them.
+	Disabled bool `xml:"-"`
+
+	ID              string `xml:"id,attr"`
+	Format          string `xml:"format"`
+	Type            string `xml:"type"`
+	Subject         string `xml:"subject"`
+	Description     string `xml:"description"`
+	Identifier      string `xml:"identifier"`
+	Encoding        string `xml:"encoding"`
+	CountryCode     string `xml:"country"`
+	Region          string `xml:"region"`
+	Category        string `xml:"category"`
+	Ranking         int    `xml:"ranking"`
+	Language        string `xml:"language"`
+	UpdatePeriod    string `xml:"schedule>updatePeriod"`
+	UpdateFrequency int    `xml:"schedule>updateFrequency"`
+	Feeds           *Feeds `xml:"feed"`
 }
 
 func (e *Channel) setEncoding() {
@@ -168,13 +236,15 @@ func (e *Channel) setEncoding() {
 // Feeds reprents a collection of feeds within an EMM channel.
 type Feeds []Feed
 
-// Add appends a new Feed to the feed collections.
+// Add appends a new Feed to the feed collections unless a feed with the
+// same URL is already present.
 func (f *Feeds) Add(other Feed) {
 	for _, feed := range *f {
-		if feed.URL != other.URL {
-			*f = append(*f, other)
+		if feed.URL == other.URL {
+			return
 		}
 	}
+	*f = append(*f, other)
 }
 
 // Feed represents a channel feed.
@@ -1,6 +1,7 @@
 package emm
 
 import (
+	"bytes"
 	"encoding/xml"
 	"io/ioutil"
 	"strings"
@@ -87,6 +88,14 @@ func TestNewChannel(t *testing.T) {
 	if c.ID != "ResearchBlog" && c.Identifier != "https://www.zscalaer.com/" {
 		t.Errorf("NewChannel() = %s; want ReserchBlog", c.ID)
 	}
+	if c.Format != "rss" {
+		t.Errorf("NewChannel() format = %q; want rss", c.Format)
+	}
+	atom := *rssFeed
+	atom.Format = rss.FormatAtom
+	if c := NewChannel(&atom, "Public"); c.Format != "atom" {
+		t.Errorf("NewChannel() of an Atom feed format = %q; want atom", c.Format)
+	}
 }
 
 func TestAdd(t *testing.T) {
@@ -97,4 +106,65 @@ func TestAdd(t *testing.T) {
 	if len(d.Channels) != 2 {
 		t.Errorf("Channel wasn't properly added.")
 	}
+	// unlike Insert, Add keeps channels whose feeds are in the directory
+	other := NewChannel(rssFeed, d.Instance)
+	other.Identifier = "https://other.example.com/"
+	d.Add(other)
+	if len(d.Channels) != 3 {
+		t.Errorf("Add() of a channel with known feeds left %d channels; want 3", len(d.Channels))
+	}
+}
+
+func TestDumpDisabled(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(NewChannel(rssFeed, d.Instance))
+	d.Channels[0].Disabled = true
+	var buf bytes.Buffer
+	if err := d.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), d.Channels[0].ID) || !strings.Contains(buf.String(), d.Channels[1].ID) {
+		t.Errorf("Dump() with %s disabled wrote:\n%s", d.Channels[0].ID, buf.String())
+	}
+	if len(d.Channels) != 2 {
+		t.Errorf("Dump() changed the directory")
+	}
+}
+
+func TestFeedsAdd(t *testing.T) {
+	feeds := Feeds{{URL: feedURL("http://a.example/feed")}, {URL: feedURL("http://b.example/feed")}}
+	feeds.Add(Feed{URL: feedURL("http://c.example/feed")})
+	feeds.Add(Feed{URL: feedURL("http://a.example/feed")})
+	if len(feeds) != 3 {
+		t.Errorf("Add() left %d feeds; want 3, each feed once", len(feeds))
+	}
+	var empty Feeds
+	empty.Add(Feed{URL: feedURL("http://a.example/feed")})
+	if len(empty) != 1 {
+		t.Errorf("Add() to no feeds left %d feeds; want 1", len(empty))
+	}
+}
+
+func TestInsert(t *testing.T) {
+	d := newDirectory(cd)
+	tests := []struct {
+		identifier, feed string
+		want             string
+		wantID           string
+	}{
+		{"http://www.malekal.com/", "http://www.malekal.com/feed/", InsertDuplicate, "P_malekalssite"},
+		{"http://malekal.example/", "http://www.malekal.com/feed/", InsertDuplicate, "P_malekalssite"},
+		{"http://www.malekal.com/", "http://www.malekal.com/news/feed/", InsertMerged, "P_malekalssite"},
+		{"http://new.example/", "http://new.example/feed/", InsertNew, "new"},
+	}
+	for _, test := range tests {
+		feeds := Feeds{{URL: feedURL(test.feed)}}
+		got, c := d.Insert(&Channel{ID: "new", Identifier: test.identifier, Feeds: &feeds})
+		if got != test.want || c.ID != test.wantID {
+			t.Errorf("Insert(%s, %s) = %s, %s; want %s, %s", test.identifier, test.feed, got, c.ID, test.want, test.wantID)
+		}
+	}
+	if len(d.Channels) != 2 || len(*d.Channels[0].Feeds) != 2 {
+		t.Errorf("Insert() left %d channels, %d feeds in the first; want 2, 2", len(d.Channels), len(*d.Channels[0].Feeds))
+	}
 }
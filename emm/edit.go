@@ -0,0 +1,160 @@
+package emm
+
+import "fmt"
+
+// A Selector selects channels by ID, identifier or feed URL. A channel is
+// selected if any of the values matches.
+type Selector struct {
+	IDs         []string
+	Identifiers []string
+	Feeds       []string
+}
+
+// Empty reports whether the selector has no values and so selects nothing.
+func (s Selector) Empty() bool {
+	return len(s.IDs) == 0 && len(s.Identifiers) == 0 && len(s.Feeds) == 0
+}
+
+// Match reports whether channel c is selected.
+func (s Selector) Match(c *Channel) bool {
+	if contains(s.IDs, c.ID) || contains(s.Identifiers, c.Identifier) {
+		return true
+	}
+	for _, f := range c.feedURLs() {
+		if contains(s.Feeds, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the selected channels.
+func (d *Directory) Select(s Selector) Channels {
+	d.Lock()
+	defer d.Unlock()
+	var cs Channels
+	for _, c := range d.Channels {
+		if s.Match(c) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// Remove removes the selected channels and returns them.
+func (d *Directory) Remove(s Selector) Channels {
+	d.Lock()
+	defer d.Unlock()
+	var removed Channels
+	kept := d.Channels[:0]
+	for _, c := range d.Channels {
+		if s.Match(c) {
+			removed = append(removed, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	d.Channels = kept
+	return removed
+}
+
+// RemoveFeed removes the feed with the given URL from every channel and
+// returns the channels changed. Channels are kept even if it was their
+// last feed.
+func (d *Directory) RemoveFeed(feedURL string) Channels {
+	d.Lock()
+	defer d.Unlock()
+	var changed Channels
+	for _, c := range d.Channels {
+		if c.Feeds == nil || !contains(c.feedURLs(), feedURL) {
+			continue
+		}
+		feeds := Feeds{}
+		for _, f := range *c.Feeds {
+			if feedKey(f) != feedURL {
+				feeds = append(feeds, f)
+			}
+		}
+		c.Feeds = &feeds
+		changed = append(changed, c)
+	}
+	return changed
+}
+
+// SetField sets the named field of the selected channels to value and
+// returns the channels changed. It fails, leaving the directory unchanged,
+// if a changed ID would be used by another channel.
+func (d *Directory) SetField(s Selector, field, value string) (Channels, error) {
+	if _, err := (&Channel{}).Get(field); err != nil {
+		return nil, err
+	}
+	d.Lock()
+	defer d.Unlock()
+	edited := make(map[*Channel]*Channel)
+	for _, c := range d.Channels {
+		if !s.Match(c) {
+			continue
+		}
+		if v, _ := c.Get(field); v == value {
+			continue
+		}
+		e := c.Clone()
+		if err := e.Set(field, value); err != nil {
+			return nil, fmt.Errorf("Channel %s: %s", c.ID, err)
+		}
+		edited[c] = e
+	}
+	return d.swap(edited)
+}
+
+// swap checks that the edited copies of channels leave every ID used by
+// one channel and only then copies them into the directory. It returns the
+// channels changed in directory order.
+func (d *Directory) swap(edited map[*Channel]*Channel) (Channels, error) {
+	n := make(map[string]int)
+	for _, c := range d.Channels {
+		if e, ok := edited[c]; ok {
+			c = e
+		}
+		n[c.ID]++
+	}
+	for _, c := range d.Channels {
+		if e, ok := edited[c]; ok && e.ID != c.ID && n[e.ID] > 1 {
+			return nil, fmt.Errorf("Channel ID %s would be used by %d channels", e.ID, n[e.ID])
+		}
+	}
+	var changed Channels
+	for _, c := range d.Channels {
+		if e, ok := edited[c]; ok {
+			*c = *e
+			changed = append(changed, c)
+		}
+	}
+	return changed, nil
+}
+
+// SetDisabled disables or enables the selected channels and returns the
+// channels changed.
+func (d *Directory) SetDisabled(s Selector, disabled bool) Channels {
+	d.Lock()
+	defer d.Unlock()
+	var changed Channels
+	for _, c := range d.Channels {
+		if s.Match(c) && c.Disabled != disabled {
+			c.Disabled = disabled
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// Clone returns a copy of the directory that shares no channels with it.
+func (d *Directory) Clone() *Directory {
+	d.Lock()
+	defer d.Unlock()
+	c := &Directory{Instance: d.Instance, XMLName: d.XMLName}
+	for _, ch := range d.Channels {
+		c.Channels = append(c.Channels, ch.Clone())
+	}
+	return c
+}
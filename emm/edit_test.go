@@ -0,0 +1,53 @@
+package emm
+
+import "testing"
+
+func TestEdit(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(NewChannel(rssFeed, "Public"))
+	*d.Channels[0].Feeds = append(*d.Channels[0].Feeds, Feed{Title: "news", URL: feedURL("http://www.malekal.com/news/feed/")})
+	before := d.Clone()
+
+	byFeed := Selector{Feeds: []string{"http://www.malekal.com/news/feed/"}}
+	if cs := d.Select(byFeed); len(cs) != 1 || cs[0].ID != "P_malekalssite" {
+		t.Errorf("Select(feed) = %v; want P_malekalssite", cs)
+	}
+	if cs := d.Select(Selector{}); len(cs) != 0 {
+		t.Errorf("Select() of empty selector = %v; want none", cs)
+	}
+
+	cs, err := d.SetField(Selector{IDs: []string{"ResearchBlog"}}, "country", "DE")
+	if err != nil || len(cs) != 1 || d.Channels[1].CountryCode != "DE" {
+		t.Errorf("SetField(country) = %v, %v; want ResearchBlog with DE", cs, err)
+	}
+	if _, err := d.SetField(byFeed, "ranking", "high"); err == nil {
+		t.Errorf("SetField(ranking, high) returned no error")
+	}
+	both := Selector{IDs: []string{"ResearchBlog", "P_malekalssite"}}
+	if _, err := d.SetField(both, "id", "Same"); err == nil {
+		t.Errorf("SetField(id) of two channels returned no error")
+	}
+	if _, err := d.SetField(byFeed, "id", "ResearchBlog"); err == nil {
+		t.Errorf("SetField(id) to a used ID returned no error")
+	}
+	if d.Channels[0].ID != "P_malekalssite" || d.Channels[1].ID != "ResearchBlog" {
+		t.Errorf("SetField(id) of a duplicate ID changed the directory to %s, %s", d.Channels[0].ID, d.Channels[1].ID)
+	}
+	if cs := d.RemoveFeed("http://www.malekal.com/news/feed/"); len(cs) != 1 || len(*cs[0].Feeds) != 1 {
+		t.Errorf("RemoveFeed() = %v; want one channel with one feed left", cs)
+	}
+	if cs := d.SetDisabled(Selector{Identifiers: []string{"http://www.malekal.com/"}}, true); len(cs) != 1 || !cs[0].Disabled {
+		t.Errorf("SetDisabled() = %v; want one disabled channel", cs)
+	}
+	if cs := d.Remove(Selector{IDs: []string{"ResearchBlog"}}); len(cs) != 1 || len(d.Channels) != 1 {
+		t.Errorf("Remove() = %v; want ResearchBlog removed", cs)
+	}
+
+	diffs := Diff(before, d)
+	if len(diffs) != 2 || diffs[0].Status != Modified || diffs[1].Status != Removed {
+		t.Errorf("Diff() after edits = %v; want modified and removed", diffs)
+	}
+	if len(before.Channels) != 2 || before.Channels[0].Disabled {
+		t.Errorf("Clone() shares channels with the directory")
+	}
+}
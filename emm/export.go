@@ -0,0 +1,288 @@
+package emm
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/certeu/emmchan/opml"
+)
+
+// FeedsColumn is the column holding the feed URLs of a channel, separated
+// by spaces, in addition to the channel fields.
+const FeedsColumn = "feeds"
+
+// Column returns the value of the named column, a channel field or
+// FeedsColumn.
+func (e *Channel) Column(name string) (string, error) {
+	if name == FeedsColumn {
+		return strings.Join(e.feedURLs(), " "), nil
+	}
+	return e.Get(name)
+}
+
+// checkColumns returns an error if any column is unknown.
+func checkColumns(columns []string) error {
+	for _, c := range columns {
+		if _, err := (&Channel{}).Column(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A ChannelRecord is the JSON form of a channel. Its schema is stable: all
+// fields are always present and new fields are only ever added.
+type ChannelRecord struct {
+	ID              string       `json:"id"`
+	Disabled        bool         `json:"disabled"`
+	Format          string       `json:"format"`
+	Type            string       `json:"type"`
+	Subject         string       `json:"subject"`
+	Description     string       `json:"description"`
+	Identifier      string       `json:"identifier"`
+	Encoding        string       `json:"encoding"`
+	Country         string       `json:"country"`
+	Region          string       `json:"region"`
+	Category        string       `json:"category"`
+	Ranking         int          `json:"ranking"`
+	Language        string       `json:"language"`
+	UpdatePeriod    string       `json:"updatePeriod"`
+	UpdateFrequency int          `json:"updateFrequency"`
+	Feeds           []FeedRecord `json:"feeds"`
+}
+
+// A FeedRecord is the JSON form of a channel feed.
+type FeedRecord struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Record returns the JSON form of the channel.
+func (e *Channel) Record() *ChannelRecord {
+	r := &ChannelRecord{
+		ID:              e.ID,
+		Disabled:        e.Disabled,
+		Format:          e.Format,
+		Type:            e.Type,
+		Subject:         e.Subject,
+		Description:     e.Description,
+		Identifier:      e.Identifier,
+		Encoding:        e.Encoding,
+		Country:         e.CountryCode,
+		Region:          e.Region,
+		Category:        e.Category,
+		Ranking:         e.Ranking,
+		Language:        e.Language,
+		UpdatePeriod:    e.UpdatePeriod,
+		UpdateFrequency: e.UpdateFrequency,
+		Feeds:           []FeedRecord{},
+	}
+	if e.Feeds != nil {
+		for _, f := range *e.Feeds {
+			r.Feeds = append(r.Feeds, FeedRecord{f.Title, feedKey(f)})
+		}
+	}
+	return r
+}
+
+// WriteTable writes the channels to w as a table with the given columns.
+func WriteTable(w io.Writer, cs Channels, columns []string) error {
+	if err := checkColumns(columns); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, c := range cs {
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			row[i], _ = c.Column(col)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the channels to w as CSV with a header row and the given
+// columns.
+func WriteCSV(w io.Writer, cs Channels, columns []string) error {
+	if err := checkColumns(columns); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(columns)
+	for _, c := range cs {
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			row[i], _ = c.Column(col)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONLines writes the channels to w as JSON lines, one ChannelRecord
+// per line.
+func WriteJSONLines(w io.Writer, cs Channels) error {
+	enc := json.NewEncoder(w)
+	for _, c := range cs {
+		if err := enc.Encode(c.Record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteXML writes the channels to w as channel elements of the channel
+// directory XML, without the enclosing directory element.
+func WriteXML(w io.Writer, cs Channels) error {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	for _, c := range cs {
+		if err := enc.EncodeElement(c, xml.StartElement{Name: xml.Name{Local: "channel"}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	if len(cs) > 0 {
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	return nil
+}
+
+// ExportColumns are the default columns of ExportCSV.
+var ExportColumns = append(append([]string(nil), Fields...), FeedsColumn)
+
+// perFeed returns a copy of each channel per feed, holding only that feed.
+// Channels without feeds are kept as they are. All exporters write one
+// record per feed, so that every record can be imported again as one input.
+func perFeed(cs Channels) Channels {
+	var out Channels
+	for _, c := range cs {
+		if c.Feeds == nil || len(*c.Feeds) < 2 {
+			out = append(out, c)
+			continue
+		}
+		for _, f := range *c.Feeds {
+			fc := *c
+			fc.Feeds = &Feeds{f}
+			out = append(out, &fc)
+		}
+	}
+	return out
+}
+
+// ExportCSV writes the directory to w as CSV with the given columns, or
+// ExportColumns if none are given, one row per feed.
+func (d *Directory) ExportCSV(w io.Writer, columns []string) error {
+	if len(columns) == 0 {
+		columns = ExportColumns
+	}
+	d.Lock()
+	defer d.Unlock()
+	return WriteCSV(w, perFeed(d.Channels), columns)
+}
+
+// ExportJSON writes the directory to w as a JSON array of ChannelRecord,
+// one per feed.
+func (d *Directory) ExportJSON(w io.Writer) error {
+	d.Lock()
+	defer d.Unlock()
+	rs := []*ChannelRecord{}
+	for _, c := range perFeed(d.Channels) {
+		rs = append(rs, c.Record())
+	}
+	out, err := json.MarshalIndent(rs, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// ExportJSONLines writes the directory to w as JSON lines, one
+// ChannelRecord per feed and line.
+func (d *Directory) ExportJSONLines(w io.Writer) error {
+	d.Lock()
+	defer d.Unlock()
+	return WriteJSONLines(w, perFeed(d.Channels))
+}
+
+// ExportOPML writes the directory to w as OPML 2.0 with one outline per
+// feed. The outlines are grouped in folders by the value of the channel
+// field groupBy, such as category or country; without groupBy they are
+// not grouped. Channels without a value are not grouped either.
+func (d *Directory) ExportOPML(w io.Writer, title, groupBy string) error {
+	if groupBy != "" {
+		if _, err := (&Channel{}).Get(groupBy); err != nil {
+			return err
+		}
+	}
+	d.Lock()
+	defer d.Unlock()
+	o := &opml.OPML{
+		Version: "2.0",
+		Head:    opml.Head{Title: title, DateCreated: time.Now().UTC().Format(time.RFC1123Z)},
+	}
+	folders := make(map[string]*opml.Outline)
+	var names []string
+	var ungrouped []*opml.Outline
+	for _, c := range d.Channels {
+		outlines := c.outlines()
+		var group string
+		if groupBy != "" {
+			group, _ = c.Get(groupBy)
+		}
+		if group == "" {
+			ungrouped = append(ungrouped, outlines...)
+			continue
+		}
+		f := folders[group]
+		if f == nil {
+			f = &opml.Outline{Text: group}
+			folders[group] = f
+			names = append(names, group)
+		}
+		f.Outlines = append(f.Outlines, outlines...)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		o.Body.Outlines = append(o.Body.Outlines, folders[n])
+	}
+	o.Body.Outlines = append(o.Body.Outlines, ungrouped...)
+	return o.Write(w)
+}
+
+// outlines returns the OPML outlines of the channel feeds. Feeds without
+// a title take the channel description.
+func (e *Channel) outlines() []*opml.Outline {
+	var outs []*opml.Outline
+	if e.Feeds == nil {
+		return outs
+	}
+	for _, f := range *e.Feeds {
+		text := f.Title
+		if text == "" {
+			text = e.Description
+		}
+		outs = append(outs, &opml.Outline{
+			Text:     text,
+			Title:    text,
+			Type:     "rss",
+			XMLURL:   feedKey(f),
+			HTMLURL:  e.Identifier,
+			Language: e.Language,
+		})
+	}
+	return outs
+}
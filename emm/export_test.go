@@ -0,0 +1,108 @@
+package emm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/certeu/emmchan/opml"
+)
+
+func TestWriters(t *testing.T) {
+	d := newDirectory(cd)
+	*d.Channels[0].Feeds = append(*d.Channels[0].Feeds, Feed{Title: "news", URL: feedURL("http://www.malekal.com/news/feed/")})
+
+	var b bytes.Buffer
+	if err := WriteCSV(&b, d.Channels, []string{"id", "ranking", FeedsColumn}); err != nil {
+		t.Fatalf("WriteCSV() returned error %s", err)
+	}
+	want := "id,ranking,feeds\nP_malekalssite,1,http://www.malekal.com/feed/ http://www.malekal.com/news/feed/\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() wrote %q; want %q", b.String(), want)
+	}
+	if err := WriteCSV(&b, d.Channels, []string{"id", "nofield"}); err == nil {
+		t.Errorf("WriteCSV() with unknown column returned no error")
+	}
+
+	b.Reset()
+	if err := WriteTable(&b, d.Channels, []string{"id", "country"}); err != nil {
+		t.Fatalf("WriteTable() returned error %s", err)
+	}
+	if want := "id              country\nP_malekalssite  US\n"; b.String() != want {
+		t.Errorf("WriteTable() wrote %q; want %q", b.String(), want)
+	}
+
+	b.Reset()
+	if err := WriteJSONLines(&b, d.Channels); err != nil {
+		t.Fatalf("WriteJSONLines() returned error %s", err)
+	}
+	var r ChannelRecord
+	if err := json.Unmarshal(b.Bytes(), &r); err != nil {
+		t.Fatalf("WriteJSONLines() wrote invalid JSON: %s", err)
+	}
+	if r.ID != "P_malekalssite" || r.Country != "US" || len(r.Feeds) != 2 || r.Feeds[1].URL != "http://www.malekal.com/news/feed/" {
+		t.Errorf("WriteJSONLines() wrote %+v", r)
+	}
+
+	b.Reset()
+	if err := WriteXML(&b, d.Channels); err != nil {
+		t.Fatalf("WriteXML() returned error %s", err)
+	}
+	if s := b.String(); !strings.HasPrefix(s, `<channel id="P_malekalssite">`) || NewDirectory("<directory>" + s + "</directory>").Channels[0].Ranking != 1 {
+		t.Errorf("WriteXML() wrote %q", s)
+	}
+}
+
+func TestExport(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(NewChannel(rssFeed, "Public"))
+	*d.Channels[0].Feeds = append(*d.Channels[0].Feeds, Feed{Title: "news", URL: feedURL("http://www.malekal.com/news/feed/")})
+	d.Channels[1].Category = "Blogs"
+
+	var b bytes.Buffer
+	if err := d.ExportCSV(&b, nil); err != nil {
+		t.Fatalf("ExportCSV() returned error %s", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 4 ||
+		lines[0] != strings.Join(ExportColumns, ",") {
+		t.Errorf("ExportCSV() wrote %q", b.String())
+	}
+
+	b.Reset()
+	if err := d.ExportJSON(&b); err != nil {
+		t.Fatalf("ExportJSON() returned error %s", err)
+	}
+	var rs []ChannelRecord
+	if err := json.Unmarshal(b.Bytes(), &rs); err != nil || len(rs) != 3 || len(rs[0].Feeds) != 1 ||
+		rs[1].ID != rs[0].ID || rs[1].Feeds[0].Title != "news" || rs[2].Feeds[0].Title != "Research Blog" {
+		t.Errorf("ExportJSON() wrote %q, %v", b.String(), err)
+	}
+
+	b.Reset()
+	if err := d.ExportOPML(&b, "channels", "category"); err != nil {
+		t.Fatalf("ExportOPML() returned error %s", err)
+	}
+	o, err := opml.NewOPML(b.Bytes())
+	if err != nil {
+		t.Fatalf("ExportOPML() wrote invalid OPML: %s", err)
+	}
+	if len(o.Body.Outlines) != 2 || o.Body.Outlines[0].Text != "Blogs" || o.Body.Outlines[1].Text != "Specialist" {
+		t.Fatalf("ExportOPML() folders = %+v; want Blogs and Specialist", o.Body.Outlines)
+	}
+	feeds := o.Body.Outlines[1].Outlines
+	if len(feeds) != 2 || feeds[1].XMLURL != "http://www.malekal.com/news/feed/" || feeds[1].HTMLURL != "http://www.malekal.com/" {
+		t.Errorf("ExportOPML() Specialist outlines = %+v; want both feeds of P_malekalssite", feeds)
+	}
+
+	b.Reset()
+	if err := d.ExportOPML(&b, "", ""); err != nil {
+		t.Fatalf("ExportOPML() without grouping returned error %s", err)
+	}
+	if o, _ := opml.NewOPML(b.Bytes()); o == nil || len(o.Body.Outlines) != 3 {
+		t.Errorf("ExportOPML() without grouping wrote %q; want 3 feeds", b.String())
+	}
+	if err := d.ExportOPML(&b, "", "nofield"); err == nil {
+		t.Errorf("ExportOPML() grouped by unknown field returned no error")
+	}
+}
@@ -0,0 +1,494 @@
+package emm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A Filter selects channels.
+type Filter interface {
+	Match(c *Channel) bool
+}
+
+// An Assignment sets a channel field to a value.
+type Assignment struct {
+	Field string
+	Value string
+}
+
+// An Edit is a parsed bulk edit expression of the form
+//
+//	set field=value[, field=value ...] [where condition]
+//
+// Conditions compare channel fields, or "feed" for any feed URL, with
+// =, !=, <, <=, >, >=, "prefix", "matches" (a glob pattern), "~" (a regular
+// expression) or "in" (a parenthesized list), and combine them with "and",
+// "or", "not" and parentheses. Values containing spaces or operator
+// characters are quoted with " or '. For example
+//
+//	set region=Europe where country in (FR, DE, BE)
+//	set ranking=3 where identifier matches *.gov.*
+type Edit struct {
+	Assignments []Assignment
+	// Where selects the channels to edit; nil selects all channels.
+	Where Filter
+}
+
+// ParseEdit parses a bulk edit expression.
+func ParseEdit(s string) (*Edit, error) {
+	p, err := newParser(s)
+	if err != nil {
+		return nil, err
+	}
+	if !p.keyword("set") {
+		return nil, p.errorf("expected set")
+	}
+	e := &Edit{}
+	for {
+		field, err := p.field(false)
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct("=") {
+			return nil, p.errorf("expected = after %s", field)
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := (&Channel{}).Set(field, value); err != nil {
+			return nil, err
+		}
+		e.Assignments = append(e.Assignments, Assignment{field, value})
+		if !p.punct(",") {
+			break
+		}
+	}
+	if p.keyword("where") {
+		if e.Where, err = p.or(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return e, nil
+}
+
+// ParseFilter parses a condition as used in the where clause of an edit.
+func ParseFilter(s string) (Filter, error) {
+	p, err := newParser(s)
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return f, nil
+}
+
+// Apply applies the edit to the directory and returns the changes made,
+// one per changed channel. It fails, leaving the directory unchanged, if a
+// changed ID would be used by another channel.
+func (e *Edit) Apply(d *Directory) ([]*ChannelDiff, error) {
+	d.Lock()
+	defer d.Unlock()
+	var diffs []*ChannelDiff
+	edited := make(map[*Channel]*Channel)
+	for _, c := range d.Channels {
+		if e.Where != nil && !e.Where.Match(c) {
+			continue
+		}
+		ed := c.Clone()
+		for _, a := range e.Assignments {
+			if err := ed.Set(a.Field, a.Value); err != nil {
+				return nil, err
+			}
+		}
+		if cd := DiffChannel(c, ed); cd != nil {
+			diffs = append(diffs, cd)
+			edited[c] = ed
+		}
+	}
+	if _, err := d.swap(edited); err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// Filter returns the channels matching filter f.
+func (d *Directory) Filter(f Filter) Channels {
+	d.Lock()
+	defer d.Unlock()
+	var cs Channels
+	for _, c := range d.Channels {
+		if f.Match(c) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// FieldFilter returns a filter comparing the named field, or "feed" for any
+// feed URL, with value using one of the operators of the edit expression
+// language other than "in".
+func FieldFilter(field, op, value string) (Filter, error) {
+	if !contains(Fields, field) && field != "feed" {
+		return nil, fmt.Errorf("Unknown channel field %q", field)
+	}
+	if !contains(cmpOps, op) && op != "prefix" && op != "matches" {
+		return nil, fmt.Errorf("Unknown operator %q", op)
+	}
+	f := &cmpFilter{field: field, op: op, value: value}
+	if err := f.compile(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// All returns a filter matching the channels matched by all of fs.
+func All(fs ...Filter) Filter {
+	return andFilter(fs)
+}
+
+// cmpOps are the comparison operators other than keywords.
+var cmpOps = []string{"=", "!=", "<", "<=", ">", ">=", "~"}
+
+type andFilter []Filter
+
+func (f andFilter) Match(c *Channel) bool {
+	for _, g := range f {
+		if !g.Match(c) {
+			return false
+		}
+	}
+	return true
+}
+
+type orFilter []Filter
+
+func (f orFilter) Match(c *Channel) bool {
+	for _, g := range f {
+		if g.Match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+type notFilter struct{ Filter }
+
+func (f notFilter) Match(c *Channel) bool {
+	return !f.Filter.Match(c)
+}
+
+// cmpFilter compares a field with a value.
+type cmpFilter struct {
+	field string
+	op    string
+	value string
+	// values of the in operator
+	values []string
+	// pattern of the matches and ~ operators
+	re *regexp.Regexp
+}
+
+// compile compiles the pattern of the matches and ~ operators.
+func (f *cmpFilter) compile() error {
+	var err error
+	switch f.op {
+	case "matches":
+		f.re, err = globRegexp(f.value)
+	case "~":
+		f.re, err = regexp.Compile(f.value)
+	}
+	return err
+}
+
+func (f *cmpFilter) Match(c *Channel) bool {
+	var vs []string
+	if f.field == "feed" {
+		vs = c.feedURLs()
+	} else {
+		v, _ := c.Get(f.field)
+		vs = []string{v}
+	}
+	for _, v := range vs {
+		if f.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *cmpFilter) matchValue(v string) bool {
+	switch f.op {
+	case "=":
+		return v == f.value
+	case "!=":
+		return v != f.value
+	case "in":
+		return contains(f.values, v)
+	case "prefix":
+		return strings.HasPrefix(v, f.value)
+	case "matches", "~":
+		return f.re.MatchString(v)
+	}
+	cmp := strings.Compare(v, f.value)
+	a, errA := strconv.Atoi(v)
+	b, errB := strconv.Atoi(f.value)
+	if errA == nil && errB == nil {
+		cmp = a - b
+	}
+	switch f.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// globRegexp translates a glob pattern, where * matches any text and ? any
+// character, into an anchored regular expression.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+type token struct {
+	text string
+	// quoted tokens are never keywords or operators
+	quoted bool
+	pos    int
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+}
+
+const operatorChars = "(),=!<>~"
+
+func newParser(s string) (*parser, error) {
+	p := &parser{src: s}
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("Unterminated string at %d in %q", i, s)
+			}
+			p.tokens = append(p.tokens, token{string(rs[i+1 : j]), true, i})
+			i = j + 1
+		case strings.ContainsRune(operatorChars, r):
+			j := i + 1
+			if j < len(rs) && rs[j] == '=' && strings.ContainsRune("!<>", r) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{string(rs[i:j]), false, i})
+			i = j
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune(operatorChars+`"'`, rs[j]) {
+				j++
+			}
+			p.tokens = append(p.tokens, token{string(rs[i:j]), false, i})
+			i = j
+		}
+	}
+	return p, nil
+}
+
+func (p *parser) done() bool {
+	return p.i == len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{pos: len(p.src)}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *parser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("Invalid expression at %d in %q: %s", p.peek().pos, p.src, fmt.Sprintf(format, a...))
+}
+
+// keyword consumes the keyword kw if it is next.
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if !p.done() && !t.quoted && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// punct consumes the operator op if it is next.
+func (p *parser) punct(op string) bool {
+	t := p.peek()
+	if !p.done() && !t.quoted && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// field consumes a field name. feed is allowed in conditions only.
+func (p *parser) field(cond bool) (string, error) {
+	t := p.peek()
+	if p.done() || t.quoted || !(contains(Fields, t.text) || cond && t.text == "feed") {
+		return "", p.errorf("expected a channel field")
+	}
+	p.i++
+	return t.text, nil
+}
+
+func (p *parser) value() (string, error) {
+	t := p.peek()
+	if p.done() || !t.quoted && strings.ContainsAny(t.text, operatorChars) {
+		return "", p.errorf("expected a value")
+	}
+	p.i++
+	return t.text, nil
+}
+
+func (p *parser) or() (Filter, error) {
+	var fs orFilter
+	for {
+		f, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+		if !p.keyword("or") {
+			break
+		}
+	}
+	if len(fs) == 1 {
+		return fs[0], nil
+	}
+	return fs, nil
+}
+
+func (p *parser) and() (Filter, error) {
+	var fs andFilter
+	for {
+		f, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+		if !p.keyword("and") {
+			break
+		}
+	}
+	if len(fs) == 1 {
+		return fs[0], nil
+	}
+	return fs, nil
+}
+
+func (p *parser) not() (Filter, error) {
+	if p.keyword("not") {
+		f, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{f}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Filter, error) {
+	if p.punct("(") {
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(")") {
+			return nil, p.errorf("expected )")
+		}
+		return f, nil
+	}
+
+	field, err := p.field(true)
+	if err != nil {
+		return nil, err
+	}
+	f := &cmpFilter{field: field}
+	switch {
+	case p.keyword("in"):
+		f.op = "in"
+		if !p.punct("(") {
+			return nil, p.errorf("expected ( after in")
+		}
+		for {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			f.values = append(f.values, v)
+			if p.punct(")") {
+				return f, nil
+			}
+			if !p.punct(",") {
+				return nil, p.errorf("expected , or )")
+			}
+		}
+	case p.keyword("matches"):
+		f.op = "matches"
+	case p.keyword("prefix"):
+		f.op = "prefix"
+	default:
+		for _, op := range cmpOps {
+			if p.punct(op) {
+				f.op = op
+				break
+			}
+		}
+		if f.op == "" {
+			return nil, p.errorf("expected an operator after %s", field)
+		}
+	}
+
+	if f.value, err = p.value(); err != nil {
+		return nil, err
+	}
+	if err := f.compile(); err != nil {
+		return nil, p.errorf("%s", err)
+	}
+	return f, nil
+}
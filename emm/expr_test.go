@@ -0,0 +1,127 @@
+package emm
+
+import "testing"
+
+func TestParseFilter(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(NewChannel(rssFeed, "Public"))
+
+	tests := []struct {
+		cond string
+		want []string
+	}{
+		{"country = US", []string{"P_malekalssite", "ResearchBlog"}},
+		{"id != ResearchBlog", []string{"P_malekalssite"}},
+		{"identifier matches *.malekal.*", []string{"P_malekalssite"}},
+		{"identifier ~ '^https://'", []string{"ResearchBlog"}},
+		{"feed matches */feed/", []string{"P_malekalssite"}},
+		{"description in (\"malekals site\", other)", []string{"P_malekalssite"}},
+		{"updateFrequency >= 3", []string{"ResearchBlog"}},
+		{"not (id = ResearchBlog or ranking > 1)", []string{"P_malekalssite"}},
+		{"country = US and NOT ranking < 2", nil},
+	}
+	for _, test := range tests {
+		f, err := ParseFilter(test.cond)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) returned error %s", test.cond, err)
+			continue
+		}
+		var got []string
+		for _, c := range d.Filter(f) {
+			got = append(got, c.ID)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("ParseFilter(%q) selects %v; want %v", test.cond, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("ParseFilter(%q) selects %v; want %v", test.cond, got, test.want)
+				break
+			}
+		}
+	}
+
+	for _, cond := range []string{"", "country", "country == US", "nofield = 1",
+		"country in (FR", "(country = US", "id = 'open", "id ~ (", "id = a b"} {
+		if _, err := ParseFilter(cond); err == nil {
+			t.Errorf("ParseFilter(%q) returned no error", cond)
+		}
+	}
+}
+
+func TestEditApply(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(NewChannel(rssFeed, "Public"))
+
+	e, err := ParseEdit("set region=Europe, ranking=3 where identifier matches *.malekal.*")
+	if err != nil {
+		t.Fatalf("ParseEdit() returned error %s", err)
+	}
+	diffs, err := e.Apply(d)
+	if err != nil || len(diffs) != 1 || diffs[0].ID != "P_malekalssite" || len(diffs[0].Fields) != 2 {
+		t.Errorf("Apply() = %v, %v; want region and ranking of P_malekalssite changed", diffs, err)
+	}
+	if c := d.Channels[0]; c.Region != "Europe" || c.Ranking != 3 {
+		t.Errorf("Apply() left region %q, ranking %d", c.Region, c.Ranking)
+	}
+	if diffs, _ := e.Apply(d); len(diffs) != 0 {
+		t.Errorf("Apply() again = %v; want no changes", diffs)
+	}
+
+	e, err = ParseEdit("SET category='General News'")
+	if err != nil {
+		t.Fatalf("ParseEdit() returned error %s", err)
+	}
+	if diffs, _ := e.Apply(d); len(diffs) != 2 || d.Channels[1].Category != "General News" {
+		t.Errorf("Apply() without where = %v; want both channels changed", diffs)
+	}
+	if e, err = ParseEdit("set id=P_malekalssite"); err != nil {
+		t.Fatalf("ParseEdit() returned error %s", err)
+	}
+	if _, err := e.Apply(d); err == nil {
+		t.Errorf("Apply() of a duplicate ID returned no error")
+	}
+	if d.Channels[0].ID != "P_malekalssite" || d.Channels[1].ID != "ResearchBlog" {
+		t.Errorf("Apply() of a duplicate ID changed the directory to %s, %s", d.Channels[0].ID, d.Channels[1].ID)
+	}
+
+	for _, s := range []string{"region=Europe", "set", "set ranking=high",
+		"set feed=x", "set region=Europe where", "set region Europe"} {
+		if _, err := ParseEdit(s); err == nil {
+			t.Errorf("ParseEdit(%q) returned no error", s)
+		}
+	}
+}
+
+func TestFieldFilter(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(NewChannel(rssFeed, "Public"))
+
+	prefix, err := FieldFilter("identifier", "prefix", "https://")
+	if err != nil {
+		t.Fatalf("FieldFilter(prefix) returned error %s", err)
+	}
+	feed, err := FieldFilter("feed", "=", "http://www.malekal.com/feed/")
+	if err != nil {
+		t.Fatalf("FieldFilter(feed) returned error %s", err)
+	}
+	if cs := d.Filter(prefix); len(cs) != 1 || cs[0].ID != "ResearchBlog" {
+		t.Errorf("Filter(prefix) = %v; want ResearchBlog", cs)
+	}
+	if cs := d.Filter(feed); len(cs) != 1 || cs[0].ID != "P_malekalssite" {
+		t.Errorf("Filter(feed) = %v; want P_malekalssite", cs)
+	}
+	if cs := d.Filter(All(prefix, feed)); len(cs) != 0 {
+		t.Errorf("Filter(All(prefix, feed)) = %v; want none", cs)
+	}
+	if cs := d.Filter(All()); len(cs) != 2 {
+		t.Errorf("Filter(All()) = %v; want all channels", cs)
+	}
+
+	for _, f := range [][3]string{{"nofield", "=", "x"}, {"id", "in", "x"}, {"id", "~", "("}} {
+		if _, err := FieldFilter(f[0], f[1], f[2]); err == nil {
+			t.Errorf("FieldFilter(%q, %q, %q) returned no error", f[0], f[1], f[2])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package emm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Fields lists the names of the channel fields, as used in the channel
+// directory XML, and disabled, which is not part of it.
+var Fields = []string{
+	"id", "format", "type", "subject", "description", "identifier",
+	"encoding", "country", "region", "category", "ranking", "language",
+	"updatePeriod", "updateFrequency", "disabled",
+}
+
+// field returns a pointer to the string field with the given name.
+func (e *Channel) field(name string) *string {
+	switch name {
+	case "id":
+		return &e.ID
+	case "format":
+		return &e.Format
+	case "type":
+		return &e.Type
+	case "subject":
+		return &e.Subject
+	case "description":
+		return &e.Description
+	case "identifier":
+		return &e.Identifier
+	case "encoding":
+		return &e.Encoding
+	case "country":
+		return &e.CountryCode
+	case "region":
+		return &e.Region
+	case "category":
+		return &e.Category
+	case "language":
+		return &e.Language
+	case "updatePeriod":
+		return &e.UpdatePeriod
+	}
+	return nil
+}
+
+// Get returns the value of the named channel field.
+func (e *Channel) Get(name string) (string, error) {
+	switch name {
+	case "ranking":
+		return strconv.Itoa(e.Ranking), nil
+	case "updateFrequency":
+		return strconv.Itoa(e.UpdateFrequency), nil
+	case "disabled":
+		return strconv.FormatBool(e.Disabled), nil
+	}
+	if f := e.field(name); f != nil {
+		return *f, nil
+	}
+	return "", fmt.Errorf("Unknown channel field %q", name)
+}
+
+// Set sets the named channel field to value.
+func (e *Channel) Set(name, value string) error {
+	switch name {
+	case "ranking", "updateFrequency":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %q: %s", name, value, err)
+		}
+		if name == "ranking" {
+			e.Ranking = n
+		} else {
+			e.UpdateFrequency = n
+		}
+		return nil
+	case "disabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Invalid %s %q: %s", name, value, err)
+		}
+		e.Disabled = b
+		return nil
+	}
+	if f := e.field(name); f != nil {
+		*f = value
+		return nil
+	}
+	return fmt.Errorf("Unknown channel field %q", name)
+}
@@ -0,0 +1,188 @@
+package emm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DefaultBackups is the number of backups kept by a DirectoryFile.
+const DefaultBackups = 3
+
+// ErrChanged is returned by DirectoryFile.Save when the directory file
+// changed on disk since it was loaded.
+var ErrChanged = errors.New("Channel directory file changed on disk since it was loaded")
+
+// A DirectoryFile is a channel directory file opened for update in place.
+// It holds an advisory lock on the file, so that concurrent updates fail
+// instead of overwriting each other. The changes saved are appended to the
+// journal of the file.
+type DirectoryFile struct {
+	Path string
+	// Backups is the number of rotated backups kept, named Path.1 (the
+	// newest) to Path.N.
+	Backups int
+	// Journal is the path of the journal, Path.journal by default
+	Journal string
+	// Provenance is the path of the provenance store, Path.provenance.json
+	// by default
+	Provenance string
+	// Run is the run the changes are journaled under
+	Run  *JournalRun
+	lock *fileLock
+	// digest of the file content when loaded
+	sum [sha256.Size]byte
+	// the directory as last loaded or saved
+	saved *Directory
+}
+
+// OpenFile locks the channel directory file at path and loads it for
+// instance inst, with the disabled channels of its provenance store.
+func OpenFile(path, inst string) (*DirectoryFile, *Directory, error) {
+	l, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, nil, err
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		l.unlock()
+		return nil, nil, err
+	}
+	d := &Directory{Instance: inst}
+	if err := d.Load(bytes.NewReader(buf)); err != nil {
+		l.unlock()
+		return nil, nil, err
+	}
+	ps, err := ReadProvenance(path + ".provenance.json")
+	if err != nil {
+		l.unlock()
+		return nil, nil, fmt.Errorf("Could not read the provenance of %s: %s", path, err)
+	}
+	if err := ps.Apply(d); err != nil {
+		l.unlock()
+		return nil, nil, err
+	}
+	f := &DirectoryFile{
+		Path:       path,
+		Backups:    DefaultBackups,
+		Journal:    path + ".journal",
+		Provenance: path + ".provenance.json",
+		Run:        NewJournalRun("", ""),
+		lock:       l,
+		sum:        sha256.Sum256(buf),
+		saved:      d.Clone(),
+	}
+	return f, d, nil
+}
+
+// Save replaces the directory file with d using WriteFile, after the
+// current file is backed up and the changes are journaled. The changes are
+// then synced to the provenance store. Save fails with ErrChanged if the file changed
+// since it was loaded.
+func (f *DirectoryFile) Save(d *Directory) error {
+	if f.lock == nil {
+		return fmt.Errorf("Channel directory file %s is closed", f.Path)
+	}
+	cur, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+	if sha256.Sum256(cur) != f.sum {
+		return ErrChanged
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := d.Dump(&buf); err != nil {
+		return err
+	}
+	if err := f.backup(cur, info.Mode()); err != nil {
+		return fmt.Errorf("Could not back up %s: %s", f.Path, err)
+	}
+	// journal the changes ahead of the write and drop them again if it
+	// fails, so that the journal records exactly the changes saved
+	var size int64
+	if fi, err := os.Stat(f.Journal); err == nil {
+		size = fi.Size()
+	}
+	entries := f.Run.Entries(Track(f.saved, d))
+	if err := AppendJournal(f.Journal, entries); err != nil {
+		os.Truncate(f.Journal, size)
+		return fmt.Errorf("Could not journal the changes to %s: %s", f.Path, err)
+	}
+	if err := WriteFile(f.Path, buf.Bytes(), info.Mode()); err != nil {
+		os.Truncate(f.Journal, size)
+		return err
+	}
+	f.sum = sha256.Sum256(buf.Bytes())
+	f.saved = d.Clone()
+	ps, err := ReadProvenance(f.Provenance)
+	if err == nil {
+		ps.Sync(f.Run, entries)
+		err = ps.Write(f.Provenance)
+	}
+	if err != nil {
+		return fmt.Errorf("Could not update the provenance of %s: %s", f.Path, err)
+	}
+	return nil
+}
+
+// WriteFile replaces the file at path with buf and sets its mode. buf is
+// written and synced to a temporary file next to path, which is renamed
+// over it, so that the file is never left partly written.
+func WriteFile(path string, buf []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// backup rotates the backups and writes content as newest backup.
+func (f *DirectoryFile) backup(content []byte, mode os.FileMode) error {
+	if f.Backups <= 0 {
+		return nil
+	}
+	name := func(i int) string {
+		return fmt.Sprintf("%s.%d", f.Path, i)
+	}
+	os.Remove(name(f.Backups))
+	for i := f.Backups - 1; i >= 1; i-- {
+		if err := os.Rename(name(i), name(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return ioutil.WriteFile(name(1), content, mode)
+}
+
+// Close releases the lock on the directory file.
+func (f *DirectoryFile) Close() error {
+	if f.lock == nil {
+		return nil
+	}
+	err := f.lock.unlock()
+	f.lock = nil
+	return err
+}
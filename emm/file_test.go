@@ -0,0 +1,128 @@
+package emm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emmchan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "channels.xml")
+	if err := ioutil.WriteFile(path, []byte(cd), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, d, err := OpenFile(path, "Public")
+	if err != nil {
+		t.Fatalf("OpenFile() returned error %s", err)
+	}
+	defer f.Close()
+	if len(d.Channels) != 1 {
+		t.Fatalf("OpenFile() loaded %d channels; want 1", len(d.Channels))
+	}
+	if _, _, err := OpenFile(path, "Public"); err == nil {
+		t.Errorf("OpenFile() of a locked file returned no error")
+	}
+
+	f.Backups = 2
+	for i := 0; i < 3; i++ {
+		d.Channels[0].Ranking = i + 2
+		if err := f.Save(d); err != nil {
+			t.Fatalf("Save() returned error %s", err)
+		}
+	}
+	for file, ranking := range map[string]int{path: 4, path + ".1": 3, path + ".2": 2} {
+		saved, err := FromFile(file, "Public")
+		if err != nil || saved.Channels[0].Ranking != ranking {
+			t.Errorf("%s has ranking %d, %v; want %d", filepath.Base(file), saved.Channels[0].Ranking, err, ranking)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Save() kept more than 2 backups")
+	}
+	if ms, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(ms) != 0 {
+		t.Errorf("Save() left temporary files %v", ms)
+	}
+
+	// a directory can not be journaled to
+	journal := f.Journal
+	f.Journal = dir
+	d.Channels[0].Ranking = 9
+	if err := f.Save(d); err == nil {
+		t.Errorf("Save() with a failing journal returned no error")
+	}
+	if saved, err := FromFile(path, "Public"); err != nil || saved.Channels[0].Ranking != 4 {
+		t.Errorf("Save() with a failing journal replaced the directory file")
+	}
+	f.Journal = journal
+
+	if err := ioutil.WriteFile(path, []byte(cd), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(d); err != ErrChanged {
+		t.Errorf("Save() of a file changed on disk returned %v; want ErrChanged", err)
+	}
+
+	f.Close()
+	f2, _, err := OpenFile(path, "Public")
+	if err != nil {
+		t.Fatalf("OpenFile() after Close() returned error %s", err)
+	}
+	f2.Close()
+}
+
+func TestDirectoryFileDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emmchan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "channels.xml")
+	if err := ioutil.WriteFile(path, []byte(cd), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, d, err := OpenFile(path, "Public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Channels[0].Disabled = true
+	if err := f.Save(d); err != nil {
+		t.Fatalf("Save() returned error %s", err)
+	}
+	f.Close()
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf, []byte("<channel")) || bytes.Contains(buf, []byte("disabled")) {
+		t.Errorf("Save() wrote the disabled channel to the EMM XML:\n%s", buf)
+	}
+
+	f, d, err = OpenFile(path, "Public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if len(d.Channels) != 1 || !d.Channels[0].Disabled || d.Channels[0].Identifier != "http://www.malekal.com/" {
+		t.Fatalf("OpenFile() did not restore the disabled channel")
+	}
+	d.Channels[0].Disabled = false
+	if err := f.Save(d); err != nil {
+		t.Fatalf("Save() returned error %s", err)
+	}
+	s, err := ReadProvenance(f.Provenance)
+	if p := s[d.Channels[0].ID]; err != nil || p.Disabled != nil || p.Channel != nil {
+		t.Errorf("Save() of an enabled channel kept it disabled in the provenance store")
+	}
+	if saved, err := FromFile(path, "Public"); err != nil || len(saved.Channels) != 1 {
+		t.Errorf("Save() of an enabled channel did not write it to the EMM XML")
+	}
+}
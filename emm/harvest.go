@@ -0,0 +1,151 @@
+package emm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var (
+	// defanged URL parts as found in CERT reports, and their replacement
+	defangs = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`(?i)\bh(?:xx|\[xx\]|\*\*)p(s?)(?:\[:\]|:)`), "http$1:"},
+		{regexp.MustCompile(`\[:\]//|\[://\]`), "://"},
+		{regexp.MustCompile(`(?i)\[\.\]|\(\.\)|\{\.\}|\[dot\]|\(dot\)`), "."},
+	}
+	urlPattern  = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'{}\[\]|\\^` + "`" + `]+`)
+	hrefPattern = regexp.MustCompile(`(?i)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	// separates the messages of an mbox file
+	mboxFrom = regexp.MustCompile(`(?m)^From .*\r?\n`)
+	// the first header line of an e-mail message
+	mailHeader = regexp.MustCompile(`(?i)^(?:Return-Path|Received|From|To|Subject|Date|Message-ID|MIME-Version|Delivered-To):`)
+)
+
+// HarvestURLs returns the http and https URLs found in free text, HTML
+// anchors, e-mail messages, mbox files or Netscape bookmark files. Defanged
+// URLs, such as hxxp://example[.]com/, are refanged. The URLs are returned
+// without duplicates in order of appearance.
+func HarvestURLs(buf []byte) []string {
+	var texts [][]byte
+	switch {
+	case bytes.HasPrefix(buf, []byte("From ")):
+		for _, m := range mboxFrom.Split(string(buf), -1) {
+			if strings.TrimSpace(m) != "" {
+				texts = append(texts, messageTexts([]byte(m))...)
+			}
+		}
+	case mailHeader.Match(buf):
+		texts = messageTexts(buf)
+	default:
+		texts = [][]byte{buf}
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, t := range texts {
+		for _, u := range harvestText(string(t)) {
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	return urls
+}
+
+// harvestText returns the URLs of anchors and of the text of s.
+func harvestText(s string) []string {
+	s = Refang(s)
+	var urls []string
+	for _, m := range hrefPattern.FindAllStringSubmatch(s, -1) {
+		u := html.UnescapeString(m[1] + m[2] + m[3])
+		if urlPattern.MatchString(u) {
+			urls = append(urls, strings.TrimSpace(u))
+		}
+	}
+	for _, u := range urlPattern.FindAllString(s, -1) {
+		urls = append(urls, trimURL(html.UnescapeString(u)))
+	}
+	return urls
+}
+
+// Refang undoes the common ways of defanging URLs in s.
+func Refang(s string) string {
+	for _, d := range defangs {
+		s = d.re.ReplaceAllString(s, d.repl)
+	}
+	return s
+}
+
+// trimURL removes the punctuation that ends the sentence of a URL in text.
+func trimURL(u string) string {
+	for {
+		t := strings.TrimRight(u, ".,;:!?")
+		if strings.HasSuffix(t, ")") && !strings.Contains(t, "(") {
+			t = t[:len(t)-1]
+		}
+		if t == u {
+			return u
+		}
+		u = t
+	}
+}
+
+// messageTexts returns the decoded text parts of an e-mail message, or the
+// message itself if it can not be parsed.
+func messageTexts(buf []byte) [][]byte {
+	m, err := mail.ReadMessage(bytes.NewReader(buf))
+	if err != nil {
+		return [][]byte{buf}
+	}
+	texts := partTexts(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body)
+	if subject := m.Header.Get("Subject"); subject != "" {
+		texts = append(texts, []byte(subject))
+	}
+	return texts
+}
+
+// partTexts returns the decoded text parts of a MIME part with the given
+// content type and transfer encoding.
+func partTexts(contentType, encoding string, body io.Reader) [][]byte {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = "text/plain"
+	}
+	if strings.HasPrefix(mt, "multipart/") {
+		var texts [][]byte
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				return texts
+			}
+			texts = append(texts, partTexts(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)...)
+		}
+	}
+	if !strings.HasPrefix(mt, "text/") {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil && len(b) == 0 {
+		return nil
+	}
+	return [][]byte{b}
+}
@@ -0,0 +1,55 @@
+package emm
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestRefang(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"hxxp://example[.]com/feed", "http://example.com/feed"},
+		{"hXXps[:]//www(.)example{.}org/rss", "https://www.example.org/rss"},
+		{"h[xx]p://example[dot]com/", "http://example.com/"},
+		{"http[://]example.com/", "http://example.com/"},
+		{"no URL here", "no URL here"},
+	}
+	for _, test := range tests {
+		if got := Refang(test.in); got != test.want {
+			t.Errorf("Refang(%q) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestHarvestURLs(t *testing.T) {
+	body := base64.StdEncoding.EncodeToString([]byte(`<p><a href="https://b.example/feed?a=1&amp;b=2">feed</a></p>`))
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"text", "See hxxps://a.example[.]com/rss. Also (http://b.example/feed), and https://a.example.com/rss again.",
+			[]string{"https://a.example.com/rss", "http://b.example/feed"}},
+		{"bookmarks", `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+<DT><A HREF="https://c.example/atom.xml" ADD_DATE="1507000000">C</A>
+<DT><A HREF='ftp://d.example/'>D</A>
+</DL>`, []string{"https://c.example/atom.xml"}},
+		{"eml", "From: analyst@example.org\r\nSubject: sources\r\nMIME-Version: 1.0\r\n" +
+			"Content-Type: multipart/alternative; boundary=XX\r\n\r\n" +
+			"--XX\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n" +
+			"Feed: https://a.example/very/long/path/that/is/=\r\nsoft-broken.xml\r\n" +
+			"--XX\r\nContent-Type: text/html\r\nContent-Transfer-Encoding: base64\r\n\r\n" + body + "\r\n" +
+			"--XX\r\nContent-Type: image/png\r\n\r\nhttp://not.harvested/\r\n--XX--\r\n",
+			[]string{"https://a.example/very/long/path/that/is/soft-broken.xml", "https://b.example/feed?a=1&b=2"}},
+		{"mbox", "From a@example.org Mon Oct  2 10:00:00 2017\nSubject: one\n\nhttp://e.example/rss\n\n" +
+			"From b@example.org Mon Oct  2 11:00:00 2017\nSubject: two\n\nhttp://f.example/rss and http://e.example/rss\n",
+			[]string{"http://e.example/rss", "http://f.example/rss"}},
+	}
+	for _, test := range tests {
+		got := HarvestURLs([]byte(test.in))
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("HarvestURLs(%s) = %q; want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package emm
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// An IDScheme is a template from which channel IDs are generated. Channel
+// fields are referenced in braces, e.g. "{country}_{domain}". The known
+// fields are title, domain, country, language, instance and prefix, the ID
+// prefix of the instance.
+type IDScheme string
+
+// DefaultIDScheme is the ID scheme used for new channels.
+const DefaultIDScheme IDScheme = "{prefix}{title}"
+
+// idFields lists the fields an IDScheme may reference.
+var idFields = []string{"title", "domain", "country", "language", "instance", "prefix"}
+
+var (
+	idField  = regexp.MustCompile(`{([^{}]*)}`)
+	nonAlnum = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+)
+
+// Validate returns an error if the scheme references an unknown field or
+// references no field at all.
+func (s IDScheme) Validate() error {
+	m := idField.FindAllStringSubmatch(string(s), -1)
+	if len(m) == 0 {
+		return fmt.Errorf("ID scheme %q references no channel field", s)
+	}
+	for _, f := range m {
+		if !isIDField(f[1]) {
+			return fmt.Errorf("ID scheme %q: unknown field %q", s, f[1])
+		}
+	}
+	return nil
+}
+
+func isIDField(name string) bool {
+	for _, f := range idFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ID generates the ID of channel c within the EMM instance inst. Non
+// alphanumeric characters are stripped from every field value but the
+// instance ID prefix.
+func (s IDScheme) ID(c *Channel, inst string) string {
+	return idField.ReplaceAllStringFunc(string(s), func(f string) string {
+		var v string
+		switch f[1 : len(f)-1] {
+		case "prefix":
+			return instanceDef(inst).IDPrefix
+		case "title":
+			v = c.Title()
+		case "domain":
+			v = c.Domain()
+		case "country":
+			v = c.CountryCode
+		case "language":
+			v = c.Language
+		case "instance":
+			v = inst
+		default:
+			return f
+		}
+		return nonAlnum.ReplaceAllString(v, "")
+	})
+}
+
+// Title returns the title of the channel. It is taken from the RSS feed the
+// channel was generated from or, for loaded channels, from its first feed.
+func (e *Channel) Title() string {
+	if e.Feed != nil && e.Feed.Channel != nil {
+		if e.Feed.Channel.Title != "" {
+			return e.Feed.Channel.Title
+		}
+		return e.Feed.Channel.URL
+	}
+	if e.Feeds != nil && len(*e.Feeds) > 0 {
+		f := (*e.Feeds)[0]
+		if f.Title != "" {
+			return f.Title
+		}
+		u := url.URL(f.URL)
+		return u.String()
+	}
+	return e.Description
+}
+
+// Domain returns the host name of the channel identifier without a leading
+// "www.".
+func (e *Channel) Domain() string {
+	host := ""
+	if u, err := url.Parse(e.Identifier); err == nil {
+		host = u.Hostname()
+	}
+	if host == "" && e.Feeds != nil && len(*e.Feeds) > 0 {
+		u := url.URL((*e.Feeds)[0].URL)
+		host = u.Hostname()
+	}
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// IDChange records the renaming of a channel.
+type IDChange struct {
+	Old string
+	New string
+}
+
+// Migrate regenerates the ID of every channel in the directory under the
+// scheme s and returns the IDs that changed, in directory order. A numeric
+// suffix is appended to IDs that would otherwise clash.
+func (d *Directory) Migrate(s IDScheme) []IDChange {
+	d.Lock()
+	defer d.Unlock()
+	var changes []IDChange
+	taken := make(map[string]bool)
+	for _, c := range d.Channels {
+		id := uniqueID(s.ID(c, d.Instance), taken)
+		taken[id] = true
+		if id != c.ID {
+			changes = append(changes, IDChange{c.ID, id})
+			c.ID = id
+		}
+	}
+	return changes
+}
+
+func uniqueID(id string, taken map[string]bool) string {
+	if !taken[id] {
+		return id
+	}
+	for i := 2; ; i++ {
+		n := id + "_" + strconv.Itoa(i)
+		if !taken[n] {
+			return n
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package emm
+
+import "testing"
+
+func TestIDSchemeID(t *testing.T) {
+	c := NewChannel(rssFeed, "Public")
+	c.CountryCode = "BE"
+	c.Language = "fr"
+	tests := []struct {
+		scheme IDScheme
+		inst   string
+		want   string
+	}{
+		{DefaultIDScheme, "Public", "ResearchBlog"},
+		{DefaultIDScheme, "Private", "P_ResearchBlog"},
+		{DefaultIDScheme, "Unknown", "ResearchBlog"},
+		{"{country}_{domain}", "Public", "BE_zscalaercom"},
+		{"{instance}-{title}_{language}", "Private", "Private-ResearchBlog_fr"},
+	}
+	for _, test := range tests {
+		if id := test.scheme.ID(c, test.inst); id != test.want {
+			t.Errorf("IDScheme(%q).ID() = %q; want %q", test.scheme, id, test.want)
+		}
+	}
+}
+
+func TestIDSchemeValidate(t *testing.T) {
+	tests := []struct {
+		scheme IDScheme
+		ok     bool
+	}{
+		{"{title}", true},
+		{"{country}_{domain}_{language}", true},
+		{"P_", false},
+		{"{name}", false},
+	}
+	for _, test := range tests {
+		err := test.scheme.Validate()
+		if (err == nil) != test.ok {
+			t.Errorf("IDScheme(%q).Validate() = %v; want ok %v", test.scheme, err, test.ok)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	d := newDirectory(cd)
+	d.Add(&Channel{
+		ID:         "malekal2",
+		Identifier: "https://malekal.com/blog/",
+		Feeds:      &Feeds{},
+	})
+	changes := d.Migrate("{domain}")
+	want := []IDChange{
+		{"P_malekalssite", "malekalcom"},
+		{"malekal2", "malekalcom_2"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Migrate() = %v; want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Migrate()[%d] = %v; want %v", i, changes[i], want[i])
+		}
+	}
+	if d.Channels[1].ID != "malekalcom_2" {
+		t.Errorf("Channel ID = %q; want malekalcom_2", d.Channels[1].ID)
+	}
+}
@@ -0,0 +1,146 @@
+package emm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// An Instance describes an EMM instance. A Directory refers to its instance
+// by name.
+type Instance struct {
+	Name string `json:"-"`
+	// IDPrefix is prepended to the IDs of new channels.
+	IDPrefix string `json:"idPrefix,omitempty"`
+	// Subject is the default subject of new channels.
+	Subject string `json:"subject,omitempty"`
+	// Categories lists the allowed channel categories unless the
+	// vocabulary restricts them. Any category is allowed if it is empty.
+	Categories []string `json:"categories,omitempty"`
+	// Directory is the path of the instance channel directory.
+	Directory string `json:"directory,omitempty"`
+	// VocabularyFile is the path of the instance vocabulary, relative to
+	// the instance definitions file until resolved by InstancesFromFile.
+	VocabularyFile string `json:"vocabulary,omitempty"`
+	// Vocabulary restricts the values of channel fields.
+	Vocabulary Vocabulary `json:"-"`
+}
+
+// Instances maps instance names to instance definitions.
+type Instances map[string]*Instance
+
+var (
+	instMu    sync.RWMutex
+	instances = Instances{
+		"Public":  {Name: "Public"},
+		"Private": {Name: "Private", IDPrefix: "P_"},
+	}
+)
+
+// LoadInstances reads instance definitions from a JSON object mapping
+// instance names to definitions.
+func LoadInstances(r io.Reader) (Instances, error) {
+	is := Instances{}
+	if err := json.NewDecoder(r).Decode(&is); err != nil {
+		return nil, fmt.Errorf("Could not load instances: %s", err)
+	}
+	for name, i := range is {
+		i.Name = name
+	}
+	return is, nil
+}
+
+// InstancesFromFile loads instance definitions from a JSON file together
+// with their vocabularies.
+func InstancesFromFile(path string) (Instances, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	is, err := LoadInstances(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range is {
+		if i.VocabularyFile == "" {
+			continue
+		}
+		vp := i.VocabularyFile
+		if !filepath.IsAbs(vp) {
+			vp = filepath.Join(filepath.Dir(path), vp)
+		}
+		if i.Vocabulary, err = VocabularyFromFile(vp); err != nil {
+			return nil, err
+		}
+		i.VocabularyFile = vp
+	}
+	return is, nil
+}
+
+// RegisterInstances adds instance definitions to the known instances,
+// replacing definitions with the same name.
+func RegisterInstances(is Instances) {
+	instMu.Lock()
+	defer instMu.Unlock()
+	for name, i := range is {
+		i.Name = name
+		instances[name] = i
+	}
+}
+
+// LookupInstance returns the definition of the named instance. The
+// instances "Public" and "Private" are always known.
+func LookupInstance(name string) (*Instance, error) {
+	instMu.RLock()
+	defer instMu.RUnlock()
+	if i, ok := instances[name]; ok {
+		return i, nil
+	}
+	var names []string
+	for n := range instances {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("Unknown instance %q, known are %s", name, strings.Join(names, ", "))
+}
+
+// instanceDef returns the definition of the named instance or an empty
+// definition if it is not known.
+func instanceDef(name string) *Instance {
+	if i, err := LookupInstance(name); err == nil {
+		return i
+	}
+	return &Instance{Name: name}
+}
+
+// Defaults returns the profile p with the instance defaults applied.
+func (i *Instance) Defaults(p *Profile) *Profile {
+	return p.Merge(&Profile{Subject: i.Subject})
+}
+
+// Check returns an error if channel c breaks a rule of the instance.
+func (i *Instance) Check(c *Channel) error {
+	if errs := i.Violations(c); len(errs) > 0 {
+		return fmt.Errorf("Instance %s: %s", i.Name, errs[0])
+	}
+	return nil
+}
+
+// Violations returns an error for every field of channel c that breaks a
+// rule of the instance.
+func (i *Instance) Violations(c *Channel) []*TermError {
+	v := i.Vocabulary
+	if _, ok := v["category"]; !ok && len(i.Categories) > 0 {
+		v = Vocabulary{"category": i.Categories}
+		for field, terms := range i.Vocabulary {
+			v[field] = terms
+		}
+	}
+	return v.Check(c)
+}
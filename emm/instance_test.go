@@ -0,0 +1,57 @@
+package emm
+
+import (
+	"strings"
+	"testing"
+)
+
+const instanceDefs = `{
+	"Exercise": {
+		"idPrefix": "X_",
+		"subject": "exercise",
+		"categories": ["Specialist", "Government"],
+		"directory": "/srv/emm/exercise.xml"
+	}
+}`
+
+func TestInstances(t *testing.T) {
+	is, err := LoadInstances(strings.NewReader(instanceDefs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	instMu.Lock()
+	saved := make(Instances)
+	for name, i := range instances {
+		saved[name] = i
+	}
+	instMu.Unlock()
+	defer func() {
+		instMu.Lock()
+		instances = saved
+		instMu.Unlock()
+	}()
+
+	RegisterInstances(is)
+	inst, err := LookupInstance("Exercise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.Name != "Exercise" || inst.Directory != "/srv/emm/exercise.xml" {
+		t.Errorf("LookupInstance(Exercise) = %+v", inst)
+	}
+	if _, err := LookupInstance("Partner"); err == nil {
+		t.Errorf("LookupInstance(Partner) returned no error")
+	}
+
+	c := NewChannel(rssFeed, "Exercise")
+	if c.ID != "X_ResearchBlog" || c.Subject != "exercise" {
+		t.Errorf("NewChannel() = %s, %s; want X_ResearchBlog, exercise", c.ID, c.Subject)
+	}
+	if err := inst.Check(c); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	c.Category = "Blogs"
+	if err := inst.Check(c); err == nil {
+		t.Errorf("Check() with category Blogs returned no error")
+	}
+}
@@ -0,0 +1,227 @@
+package emm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"reflect"
+	"time"
+)
+
+// Journal operations.
+const (
+	JournalAdd    = "add"
+	JournalMerge  = "merge"
+	JournalRemove = "remove"
+	JournalEdit   = "edit"
+)
+
+// A JournalEntry records one change of a channel. Before and After are the
+// channel before and after the change, nil for added and removed channels.
+type JournalEntry struct {
+	Run      string         `json:"run"`
+	Time     time.Time      `json:"time"`
+	Operator string         `json:"operator"`
+	Command  string         `json:"command"`
+	Source   string         `json:"source"`
+	Op       string         `json:"op"`
+	ID       string         `json:"id"`
+	Fields   []FieldChange  `json:"fields,omitempty"`
+	Before   *ChannelRecord `json:"before"`
+	After    *ChannelRecord `json:"after"`
+}
+
+// A JournalRun is one run of a command changing a directory. Its changes are
+// journaled with the run ID, time, operator and input source.
+type JournalRun struct {
+	ID       string
+	Time     time.Time
+	Operator string
+	Command  string
+	// Source names the input of the run, Sources that of single channels
+	Source  string
+	Sources map[*Channel]string
+	// Checked lists the channels whose feeds were fetched and parsed
+	Checked Channels
+}
+
+// NewJournalRun returns a run of command started now by the current user.
+func NewJournalRun(command, source string) *JournalRun {
+	now := time.Now()
+	operator := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+	return &JournalRun{
+		ID:       fmt.Sprintf("%s-%d", now.UTC().Format("20060102T150405"), os.Getpid()),
+		Time:     now,
+		Operator: operator,
+		Command:  command,
+		Source:   source,
+		Sources:  make(map[*Channel]string),
+	}
+}
+
+// Entries returns the journal entries of the changes diffs made in the run.
+// Modified channels which only gained feeds are merges, other modified
+// channels are edits.
+func (r *JournalRun) Entries(diffs []*ChannelDiff) []*JournalEntry {
+	var es []*JournalEntry
+	for _, cd := range diffs {
+		e := &JournalEntry{
+			Run:      r.ID,
+			Time:     r.Time,
+			Operator: r.Operator,
+			Command:  r.Command,
+			Source:   r.Source,
+			ID:       cd.ID,
+			Fields:   cd.Fields,
+		}
+		if s, ok := r.Sources[cd.New]; ok {
+			e.Source = s
+		}
+		if cd.Old != nil {
+			e.Before = cd.Old.Record()
+		}
+		if cd.New != nil {
+			e.After = cd.New.Record()
+		}
+		switch {
+		case cd.Status == Added:
+			e.Op = JournalAdd
+		case cd.Status == Removed:
+			e.Op = JournalRemove
+		case cd.Fields == nil && cd.FeedsRemoved == nil:
+			e.Op = JournalMerge
+		default:
+			e.Op = JournalEdit
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// AppendJournal appends entries to the journal file at path, creating it if
+// needed.
+func AppendJournal(path string, entries []*JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadJournal reads the entries of the journal file at path. A missing
+// journal has no entries.
+func ReadJournal(path string) ([]*JournalEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var es []*JournalEntry
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 16*1024*1024)
+	for n := 1; s.Scan(); n++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		e := &JournalEntry{}
+		if err := json.Unmarshal(s.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("Invalid journal entry at line %d of %s: %s", n, path, err)
+		}
+		es = append(es, e)
+	}
+	return es, s.Err()
+}
+
+// Revert undoes the changes of entries, the last one first. It fails if a
+// channel changed since the entry that changed it.
+func (d *Directory) Revert(entries []*JournalEntry) error {
+	d.Lock()
+	defer d.Unlock()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := d.revert(entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Directory) revert(e *JournalEntry) error {
+	idx := -1
+	if e.After != nil {
+		for i, c := range d.Channels {
+			if c.ID == e.After.ID && reflect.DeepEqual(c.Record(), e.After) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("Could not revert %s of channel %s in run %s: channel changed since", e.Op, e.ID, e.Run)
+		}
+	}
+	if e.Before == nil {
+		d.Channels = append(d.Channels[:idx], d.Channels[idx+1:]...)
+		return nil
+	}
+	c, err := e.Before.Channel()
+	if err != nil {
+		return fmt.Errorf("Could not revert %s of channel %s in run %s: %s", e.Op, e.ID, e.Run, err)
+	}
+	if idx < 0 {
+		d.Channels = append(d.Channels, c)
+	} else {
+		d.Channels[idx] = c
+	}
+	return nil
+}
+
+// Channel returns the channel of the JSON form r.
+func (r *ChannelRecord) Channel() (*Channel, error) {
+	c := &Channel{
+		ID:              r.ID,
+		Disabled:        r.Disabled,
+		Format:          r.Format,
+		Type:            r.Type,
+		Subject:         r.Subject,
+		Description:     r.Description,
+		Identifier:      r.Identifier,
+		Encoding:        r.Encoding,
+		CountryCode:     r.Country,
+		Region:          r.Region,
+		Category:        r.Category,
+		Ranking:         r.Ranking,
+		Language:        r.Language,
+		UpdatePeriod:    r.UpdatePeriod,
+		UpdateFrequency: r.UpdateFrequency,
+	}
+	for _, f := range r.Feeds {
+		u, err := url.Parse(f.URL)
+		if err != nil {
+			return nil, err
+		}
+		c.addFeed(Feed{Title: f.Title, URL: FeedURL(*u)})
+	}
+	return c, nil
+}
@@ -0,0 +1,55 @@
+package emm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	d := newDirectory(cd)
+	orig := d.Clone()
+	run := NewJournalRun("set", "test")
+
+	d.Channels[0].Ranking = 5
+	added := NewChannel(rssFeed, "Public")
+	added.ID = "Added"
+	added.Identifier = "https://added.example.com/"
+	d.Add(added)
+	run.Sources[added] = "line 1"
+	es := run.Entries(Diff(orig, d))
+	if len(es) != 2 || es[0].Op != JournalEdit || es[1].Op != JournalAdd || es[1].Source != "line 1" || es[0].Source != "test" {
+		t.Fatalf("Entries() = %v; want an edit and an add", es)
+	}
+
+	dir, err := ioutil.TempDir("", "emmchan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "channels.xml.journal")
+	if err := AppendJournal(path, es[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendJournal(path, es[1:]); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadJournal(path)
+	if err != nil || len(read) != 2 || read[0].Run != run.ID || read[1].After.ID != "Added" {
+		t.Fatalf("ReadJournal() = %v, %v; want the 2 entries appended", read, err)
+	}
+
+	restored := d.Clone()
+	if err := restored.Revert(read); err != nil {
+		t.Fatalf("Revert() returned error %s", err)
+	}
+	if diffs := Diff(orig, restored); len(diffs) != 0 {
+		t.Errorf("Revert() left changes %v", diffs)
+	}
+
+	d.Channels[0].Ranking = 6
+	if err := d.Revert(read); err == nil {
+		t.Errorf("Revert() of a channel changed since returned no error")
+	}
+}
@@ -0,0 +1,125 @@
+package emm
+
+// languageCorpus holds sample text from which the language detection
+// profiles are built, keyed by ISO 639-1 code.
+var languageCorpus = map[string]string{
+	"en": `The security team published an advisory about a critical
+vulnerability in the web server that allows remote attackers to execute
+arbitrary code. Users are advised to install the latest update as soon as
+possible. The government announced new measures on Tuesday to protect the
+country against cyber attacks, and the minister said that the agency would
+work with the private sector. According to the report, the number of
+incidents has increased during the last year. Researchers discovered a new
+malware campaign which is targeting banks and financial institutions in
+several countries. The company confirmed that the data of thousands of
+customers were stolen and that the police have opened an investigation. It
+is not yet known who is behind the attack, but experts believe that the
+group has been active for years. This is the first time that such a
+warning has been issued by the authorities of the European Union.`,
+
+	"fr": `L'équipe de sécurité a publié un avis concernant une vulnérabilité
+critique dans le serveur web qui permet à des attaquants distants
+d'exécuter du code arbitraire. Il est conseillé aux utilisateurs
+d'installer la dernière mise à jour dès que possible. Le gouvernement a
+annoncé mardi de nouvelles mesures pour protéger le pays contre les
+cyberattaques, et le ministre a déclaré que l'agence travaillerait avec le
+secteur privé. Selon le rapport, le nombre d'incidents a augmenté au cours
+de la dernière année. Des chercheurs ont découvert une nouvelle campagne de
+logiciels malveillants qui vise les banques et les institutions
+financières dans plusieurs pays. L'entreprise a confirmé que les données de
+milliers de clients ont été volées et que la police a ouvert une enquête.
+On ne sait pas encore qui est derrière l'attaque, mais les experts pensent
+que le groupe est actif depuis des années.`,
+
+	"de": `Das Sicherheitsteam hat eine Warnung zu einer kritischen
+Schwachstelle im Webserver veröffentlicht, die es entfernten Angreifern
+ermöglicht, beliebigen Code auszuführen. Den Nutzern wird empfohlen, die
+neueste Aktualisierung so schnell wie möglich zu installieren. Die
+Bundesregierung hat am Dienstag neue Maßnahmen angekündigt, um das Land
+gegen Cyberangriffe zu schützen, und der Minister sagte, dass die Behörde
+mit der Wirtschaft zusammenarbeiten werde. Laut dem Bericht ist die Zahl
+der Vorfälle im letzten Jahr gestiegen. Forscher haben eine neue
+Schadsoftware entdeckt, die sich gegen Banken und Finanzinstitute in
+mehreren Ländern richtet. Das Unternehmen bestätigte, dass die Daten von
+tausenden Kunden gestohlen wurden und dass die Polizei Ermittlungen
+eingeleitet hat. Es ist noch nicht bekannt, wer hinter dem Angriff steckt,
+aber Experten glauben, dass die Gruppe seit Jahren aktiv ist.`,
+
+	"nl": `Het beveiligingsteam heeft een waarschuwing gepubliceerd over een
+kritieke kwetsbaarheid in de webserver waarmee aanvallers op afstand
+willekeurige code kunnen uitvoeren. Gebruikers wordt aangeraden de
+nieuwste update zo snel mogelijk te installeren. De regering heeft dinsdag
+nieuwe maatregelen aangekondigd om het land te beschermen tegen
+cyberaanvallen, en de minister zei dat het agentschap zal samenwerken met
+de private sector. Volgens het rapport is het aantal incidenten het
+afgelopen jaar gestegen. Onderzoekers hebben een nieuwe campagne met
+kwaadaardige software ontdekt die gericht is op banken en financiële
+instellingen in verschillende landen. Het bedrijf bevestigde dat de
+gegevens van duizenden klanten zijn gestolen en dat de politie een
+onderzoek is gestart. Het is nog niet bekend wie achter de aanval zit,
+maar deskundigen denken dat de groep al jaren actief is.`,
+
+	"es": `El equipo de seguridad ha publicado un aviso sobre una
+vulnerabilidad crítica en el servidor web que permite a atacantes remotos
+ejecutar código arbitrario. Se recomienda a los usuarios instalar la última
+actualización lo antes posible. El gobierno anunció el martes nuevas
+medidas para proteger al país contra los ciberataques, y el ministro dijo
+que la agencia trabajará con el sector privado. Según el informe, el número
+de incidentes ha aumentado durante el último año. Los investigadores
+descubrieron una nueva campaña de programas maliciosos que tiene como
+objetivo a bancos e instituciones financieras en varios países. La empresa
+confirmó que los datos de miles de clientes fueron robados y que la policía
+ha abierto una investigación. Todavía no se sabe quién está detrás del
+ataque, pero los expertos creen que el grupo lleva años activo.`,
+
+	"it": `Il gruppo di sicurezza ha pubblicato un avviso su una
+vulnerabilità critica nel server web che consente ad aggressori remoti di
+eseguire codice arbitrario. Si consiglia agli utenti di installare
+l'ultimo aggiornamento il prima possibile. Il governo ha annunciato martedì
+nuove misure per proteggere il paese dagli attacchi informatici, e il
+ministro ha detto che l'agenzia lavorerà con il settore privato. Secondo il
+rapporto, il numero degli incidenti è aumentato nel corso dell'ultimo anno.
+I ricercatori hanno scoperto una nuova campagna di software dannoso che
+prende di mira banche e istituzioni finanziarie in diversi paesi.
+L'azienda ha confermato che i dati di migliaia di clienti sono stati rubati
+e che la polizia ha aperto un'indagine. Non si sa ancora chi ci sia dietro
+l'attacco, ma gli esperti ritengono che il gruppo sia attivo da anni.`,
+
+	"pt": `A equipa de segurança publicou um aviso sobre uma vulnerabilidade
+crítica no servidor web que permite a atacantes remotos executar código
+arbitrário. Os utilizadores são aconselhados a instalar a atualização mais
+recente o mais rapidamente possível. O governo anunciou na terça-feira
+novas medidas para proteger o país contra ataques informáticos, e o
+ministro disse que a agência vai trabalhar com o setor privado. De acordo
+com o relatório, o número de incidentes aumentou durante o último ano. Os
+investigadores descobriram uma nova campanha de programas maliciosos que
+tem como alvo bancos e instituições financeiras em vários países. A
+empresa confirmou que os dados de milhares de clientes foram roubados e que
+a polícia abriu uma investigação. Ainda não se sabe quem está por trás do
+ataque, mas os especialistas acreditam que o grupo está ativo há anos.`,
+
+	"pl": `Zespół bezpieczeństwa opublikował ostrzeżenie dotyczące krytycznej
+podatności w serwerze internetowym, która pozwala zdalnym atakującym na
+wykonanie dowolnego kodu. Użytkownikom zaleca się jak najszybsze
+zainstalowanie najnowszej aktualizacji. Rząd ogłosił we wtorek nowe środki
+mające chronić kraj przed cyberatakami, a minister powiedział, że agencja
+będzie współpracować z sektorem prywatnym. Według raportu liczba
+incydentów wzrosła w ciągu ostatniego roku. Badacze odkryli nową kampanię
+złośliwego oprogramowania, której celem są banki i instytucje finansowe w
+kilku krajach. Firma potwierdziła, że dane tysięcy klientów zostały
+skradzione i że policja wszczęła śledztwo. Nie wiadomo jeszcze, kto stoi za
+atakiem, ale eksperci uważają, że grupa działa od lat.`,
+
+	"sv": `Säkerhetsteamet har publicerat en varning om en kritisk
+sårbarhet i webbservern som gör det möjligt för angripare att köra
+godtycklig kod på distans. Användare rekommenderas att installera den
+senaste uppdateringen så snart som möjligt. Regeringen meddelade på
+tisdagen nya åtgärder för att skydda landet mot cyberattacker, och
+ministern sade att myndigheten kommer att samarbeta med den privata
+sektorn. Enligt rapporten har antalet incidenter ökat under det senaste
+året. Forskare har upptäckt en ny kampanj med skadlig kod som riktar sig
+mot banker och finansiella institutioner i flera länder. Företaget
+bekräftade att uppgifter om tusentals kunder har stulits och att polisen
+har inlett en utredning. Det är ännu inte känt vem som ligger bakom
+attacken, men experter tror att gruppen har varit aktiv i flera år.`,
+}
@@ -0,0 +1,141 @@
+package emm
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// profileSize is the number of n-grams kept per language profile.
+	profileSize = 300
+	// minDetectLength is the minimum number of letters needed to detect a
+	// language.
+	minDetectLength = 20
+	// fullDetectLength is the number of letters from which on the detection
+	// confidence is not lowered for short texts.
+	fullDetectLength = 200
+)
+
+// languageProfiles holds the n-gram ranks of the languages in
+// languageCorpus.
+var languageProfiles = make(map[string]map[string]int)
+
+func init() {
+	for lang, text := range languageCorpus {
+		languageProfiles[lang] = ngramProfile(text)
+	}
+}
+
+var markup = regexp.MustCompile(`<[^>]*>|&[#a-zA-Z0-9]+;`)
+
+// DetectLanguage detects the language of text using n-gram profiles built
+// from an embedded corpus. It returns an empty inference if the text is too
+// short or the language is not known.
+func DetectLanguage(text string) *Inference {
+	text = markup.ReplaceAllString(text, " ")
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minDetectLength {
+		return &Inference{}
+	}
+
+	p := ngramProfile(text)
+	var langs []string
+	for lang := range languageProfiles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	best, second := "", -1
+	bestDist := -1
+	for _, lang := range langs {
+		d := distance(p, languageProfiles[lang])
+		switch {
+		case bestDist == -1 || d < bestDist:
+			second = bestDist
+			best, bestDist = lang, d
+		case second == -1 || d < second:
+			second = d
+		}
+	}
+
+	conf := 1.0
+	if second > 0 {
+		conf = float64(second-bestDist) / float64(second) * 10
+	}
+	if letters < fullDetectLength {
+		conf *= float64(letters) / fullDetectLength
+	}
+	if conf > 1 {
+		conf = 1
+	}
+	return &Inference{
+		Value:      best,
+		Source:     LanguageDetected,
+		Confidence: conf,
+		Signals:    []Signal{{LanguageDetected, best, conf}},
+	}
+}
+
+// ngramProfile returns the ranks of the most frequent 1 to 3-grams of the
+// words in text.
+func ngramProfile(text string) map[string]int {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		r := []rune(" " + w + " ")
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n <= len(r); i++ {
+				g := string(r[i : i+n])
+				if g != " " {
+					counts[g]++
+				}
+			}
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+	ranks := make(map[string]int, len(grams))
+	for i, g := range grams {
+		ranks[g] = i
+	}
+	return ranks
+}
+
+// distance returns the out-of-place distance of a text profile to a
+// language profile.
+func distance(text, lang map[string]int) int {
+	d := 0
+	for g, r := range text {
+		lr, ok := lang[g]
+		switch {
+		case !ok:
+			d += profileSize
+		case lr > r:
+			d += lr - r
+		default:
+			d += r - lr
+		}
+	}
+	return d
+}
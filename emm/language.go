@@ -0,0 +1,85 @@
+package emm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/certeu/emmchan/rss"
+	"golang.org/x/text/language"
+)
+
+// Sources of a channel language.
+const (
+	LanguageDeclared = "declared"
+	LanguageDetected = "detected"
+)
+
+// languageNames maps lower case English and native language names to ISO
+// 639-1 codes.
+var languageNames = map[string]string{
+	"arabic": "ar", "bulgarian": "bg", "български": "bg", "chinese": "zh",
+	"中文": "zh", "croatian": "hr", "hrvatski": "hr", "czech": "cs",
+	"čeština": "cs", "danish": "da", "dansk": "da", "dutch": "nl",
+	"nederlands": "nl", "english": "en", "estonian": "et", "eesti": "et",
+	"finnish": "fi", "suomi": "fi", "french": "fr", "français": "fr",
+	"german": "de", "deutsch": "de", "greek": "el", "ελληνικά": "el",
+	"hebrew": "he", "hungarian": "hu", "magyar": "hu", "irish": "ga",
+	"gaeilge": "ga", "italian": "it", "italiano": "it", "japanese": "ja",
+	"日本語": "ja", "korean": "ko", "한국어": "ko", "latvian": "lv",
+	"latviešu": "lv", "lithuanian": "lt", "lietuvių": "lt", "maltese": "mt",
+	"malti": "mt", "norwegian": "no", "norsk": "no", "persian": "fa",
+	"polish": "pl", "polski": "pl", "portuguese": "pt", "português": "pt",
+	"romanian": "ro", "română": "ro", "russian": "ru", "русский": "ru",
+	"slovak": "sk", "slovenčina": "sk", "slovenian": "sl",
+	"slovenščina": "sl", "spanish": "es", "español": "es", "swedish": "sv",
+	"svenska": "sv", "turkish": "tr", "türkçe": "tr", "ukrainian": "uk",
+	"українська": "uk",
+}
+
+// NormalizeLanguage returns the ISO 639-1 code, or the ISO 639-3 code for
+// languages without one, of a declared language. It accepts BCP 47 tags in
+// any case, POSIX locales such as "fr_FR.UTF-8" and language names such as
+// "English".
+func NormalizeLanguage(s string) (string, error) {
+	v := strings.TrimSpace(s)
+	if i := strings.IndexAny(v, ".@"); i != -1 {
+		v = v[:i]
+	}
+	if code, ok := languageNames[strings.ToLower(v)]; ok {
+		return code, nil
+	}
+	t, err := language.Parse(strings.Replace(v, "_", "-", -1))
+	if err != nil {
+		return "", fmt.Errorf("Invalid language %q", s)
+	}
+	b, conf := t.Base()
+	if conf != language.Exact {
+		return "", fmt.Errorf("Invalid language %q", s)
+	}
+	return b.String(), nil
+}
+
+// InferLanguage returns the language of a RSS feed. The declared language
+// is normalized; if the feed declares none, or none that can be
+// normalized, the language is detected from the item titles and
+// descriptions. The Source of the result tells which was the case.
+func InferLanguage(f *rss.Feed) *Inference {
+	declared := f.Channel.Language
+	if code, err := NormalizeLanguage(declared); err == nil {
+		return &Inference{
+			Value:      code,
+			Source:     LanguageDeclared,
+			Confidence: 1,
+			Signals:    []Signal{{LanguageDeclared, declared, 1}},
+		}
+	}
+	var text []string
+	for _, it := range f.Channel.Items {
+		text = append(text, it.Title, it.Description)
+	}
+	inf := DetectLanguage(strings.Join(text, " "))
+	if declared != "" {
+		inf.Signals = append(inf.Signals, Signal{LanguageDeclared, declared, 0})
+	}
+	return inf
+}
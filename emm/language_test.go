@@ -0,0 +1,68 @@
+package emm
+
+import (
+	"testing"
+
+	"github.com/certeu/emmchan/rss"
+)
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"en", "en"},
+		{"en-us", "en"},
+		{"EN_GB", "en"},
+		{"fr_FR.UTF-8", "fr"},
+		{"English", "en"},
+		{"Deutsch", "de"},
+		{"deu", "de"},
+		{"", ""},
+		{"klingon tongue", ""},
+	}
+	for _, test := range tests {
+		code, err := NormalizeLanguage(test.in)
+		if code != test.want || (err == nil) != (test.want != "") {
+			t.Errorf("NormalizeLanguage(%q) = %q, %v; want %q", test.in, code, err, test.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Microsoft publie des correctifs pour plusieurs failles de sécurité dans Windows et Office", "fr"},
+		{"Neue Sicherheitslücke in Routern: Hersteller empfiehlt ein sofortiges Update der Firmware", "de"},
+		{"Attackers exploit a zero-day vulnerability in popular VPN appliances, researchers warn", "en"},
+		{"Politie waarschuwt voor nieuwe golf van phishingberichten die zich voordoen als de bank", "nl"},
+		{"Ataque", ""},
+	}
+	for _, test := range tests {
+		inf := DetectLanguage(test.in)
+		if inf.Value != test.want {
+			t.Errorf("DetectLanguage(%q) = %q; want %q", test.in, inf.Value, test.want)
+		}
+	}
+}
+
+func TestInferLanguage(t *testing.T) {
+	f := &rss.Feed{Channel: &rss.Channel{
+		Language: "",
+		Items: []rss.Item{
+			{Title: "Nuova campagna di phishing contro i clienti delle banche italiane",
+				Description: "<p>I ricercatori hanno scoperto una nuova campagna che colpisce gli utenti</p>"},
+		},
+	}}
+	inf := InferLanguage(f)
+	if inf.Value != "it" || inf.Source != LanguageDetected {
+		t.Errorf("InferLanguage() = %q, %s; want it, %s", inf.Value, inf.Source, LanguageDetected)
+	}
+	f.Channel.Language = "it-IT"
+	inf = InferLanguage(f)
+	if inf.Value != "it" || inf.Source != LanguageDeclared {
+		t.Errorf("InferLanguage() = %q, %s; want it, %s", inf.Value, inf.Source, LanguageDeclared)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package emm
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// A fileLock is an advisory lock held on a lock file.
+type fileLock struct {
+	f *os.File
+}
+
+// lockFile takes an exclusive advisory lock on the file at path, creating
+// it if needed. It fails if another process holds the lock.
+func lockFile(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Could not lock %s, is it in use by another process? %s", path, err)
+	}
+	return &fileLock{f}, nil
+}
+
+func (l *fileLock) unlock() error {
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	return l.f.Close()
+}
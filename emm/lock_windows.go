@@ -0,0 +1,27 @@
+package emm
+
+import (
+	"fmt"
+	"os"
+)
+
+// A fileLock is a lock held by creating a lock file.
+type fileLock struct {
+	path string
+	f    *os.File
+}
+
+// lockFile creates the lock file at path. It fails if the file exists,
+// that is if another process holds the lock or a process holding it died.
+func lockFile(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Could not lock %s, remove it if no other process uses it: %s", path, err)
+	}
+	return &fileLock{path, f}, nil
+}
+
+func (l *fileLock) unlock() error {
+	l.f.Close()
+	return os.Remove(l.path)
+}
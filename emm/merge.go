@@ -0,0 +1,196 @@
+package emm
+
+import "fmt"
+
+// Merge conflict policies.
+const (
+	// FirstWins keeps the value of the first directory with the channel.
+	FirstWins = "first-wins"
+	// LastWins takes the value of the last directory with the channel.
+	LastWins = "last-wins"
+	// FieldPrecedence takes the value of the directory given for the field
+	// in MergeOptions.Precedence and falls back to FirstWins.
+	FieldPrecedence = "field-precedence"
+	// Fail makes Merge fail on any conflict.
+	Fail = "fail"
+)
+
+// Channel match keys.
+const (
+	MatchID         = "id"
+	MatchIdentifier = "identifier"
+	MatchFeed       = "feed"
+)
+
+// MergeOptions configure how directories are merged.
+type MergeOptions struct {
+	Policy string
+	// MatchBy lists the keys by which channels are matched, tried in order.
+	// It defaults to id, identifier and feed.
+	MatchBy []string
+	// Precedence maps field names to the index of the directory whose value
+	// is taken under the FieldPrecedence policy.
+	Precedence map[string]int
+}
+
+// A SourceValue is a field value found in one of the merged directories.
+type SourceValue struct {
+	// Source is the index of the directory.
+	Source int    `json:"source"`
+	Value  string `json:"value"`
+}
+
+// A Conflict is a channel field for which merged directories disagree.
+type Conflict struct {
+	// ID of the channel in the merged directory
+	Channel string        `json:"channel"`
+	Field   string        `json:"field"`
+	Values  []SourceValue `json:"values"`
+	Chosen  string        `json:"chosen"`
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("channel %s: %s: %v, chose %q", c.Channel, c.Field, c.Values, c.Chosen)
+}
+
+// Merge merges directories into a new one. Matching channels are merged
+// into one, with the union of their feeds and conflicting field values
+// resolved by the policy. Empty values never conflict. The conflicts are
+// returned in any case; under the Fail policy an error is returned too.
+func Merge(opts MergeOptions, dirs ...*Directory) (*Directory, []Conflict, error) {
+	if len(opts.MatchBy) == 0 {
+		opts.MatchBy = []string{MatchID, MatchIdentifier, MatchFeed}
+	}
+	for _, k := range opts.MatchBy {
+		if k != MatchID && k != MatchIdentifier && k != MatchFeed {
+			return nil, nil, fmt.Errorf("Unknown match key %q", k)
+		}
+	}
+	switch opts.Policy {
+	case FirstWins, LastWins, FieldPrecedence, Fail:
+	case "":
+		opts.Policy = FirstWins
+	default:
+		return nil, nil, fmt.Errorf("Unknown merge policy %q", opts.Policy)
+	}
+
+	out := &Directory{}
+	if len(dirs) > 0 {
+		out.Instance = dirs[0].Instance
+	}
+	// values seen per merged channel and field
+	values := make(map[*Channel]map[string][]SourceValue)
+	for src, d := range dirs {
+		for _, c := range d.Channels {
+			m := out.Channels.matchBy(c, opts.MatchBy)
+			if m == nil {
+				m = c.Clone()
+				out.Channels = append(out.Channels, m)
+				values[m] = make(map[string][]SourceValue)
+			} else {
+				m.mergeFields(c, src, opts)
+				if c.Feeds != nil {
+					for _, f := range *c.Feeds {
+						m.addFeed(f)
+					}
+				}
+			}
+			for _, f := range Fields {
+				if v, _ := c.Get(f); !isEmpty(v) {
+					values[m][f] = append(values[m][f], SourceValue{src, v})
+				}
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for _, m := range out.Channels {
+		for _, f := range Fields {
+			vs := values[m][f]
+			for i := 1; i < len(vs); i++ {
+				if vs[i].Value != vs[0].Value {
+					chosen, _ := m.Get(f)
+					conflicts = append(conflicts, Conflict{m.ID, f, vs, chosen})
+					break
+				}
+			}
+		}
+	}
+	if opts.Policy == Fail && len(conflicts) > 0 {
+		return out, conflicts, fmt.Errorf("%d merge conflicts", len(conflicts))
+	}
+	return out, conflicts, nil
+}
+
+// mergeFields sets the fields of e to those of channel c from directory
+// src as the merge policy says.
+func (e *Channel) mergeFields(c *Channel, src int, opts MergeOptions) {
+	for _, f := range Fields {
+		v, _ := c.Get(f)
+		if isEmpty(v) {
+			continue
+		}
+		cur, _ := e.Get(f)
+		take := isEmpty(cur)
+		switch opts.Policy {
+		case LastWins:
+			take = true
+		case FieldPrecedence:
+			if p, ok := opts.Precedence[f]; ok && p == src {
+				take = true
+			}
+		}
+		if take {
+			e.Set(f, v)
+		}
+	}
+}
+
+// isEmpty reports whether a field value is missing. Numeric fields are
+// missing if zero.
+func isEmpty(v string) bool {
+	return v == "" || v == "0"
+}
+
+// matchBy returns the first channel matching c by the first key that
+// matches any channel.
+func (cs Channels) matchBy(c *Channel, keys []string) *Channel {
+	for _, k := range keys {
+		for _, o := range cs {
+			switch k {
+			case MatchID:
+				if c.ID != "" && o.ID == c.ID {
+					return o
+				}
+			case MatchIdentifier:
+				if c.Identifier != "" && o.Identifier == c.Identifier {
+					return o
+				}
+			case MatchFeed:
+				for _, f := range c.feedURLs() {
+					if contains(o.feedURLs(), f) {
+						return o
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of the channel that shares no feeds with it.
+func (e *Channel) Clone() *Channel {
+	c := *e
+	if e.Feeds != nil {
+		feeds := append(Feeds(nil), *e.Feeds...)
+		c.Feeds = &feeds
+	}
+	return &c
+}
+
+func (e *Channel) addFeed(f Feed) {
+	if e.Feeds == nil {
+		e.Feeds = &Feeds{}
+	}
+	e.Feeds.Add(f)
+}
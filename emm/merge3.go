@@ -0,0 +1,128 @@
+package emm
+
+// Sources of the values of a three-way merge conflict.
+const (
+	SourceBase = iota
+	SourceOurs
+	SourceTheirs
+)
+
+// ChannelConflict is the field of conflicts on a whole channel, removed on
+// one side and modified on the other.
+const ChannelConflict = "channel"
+
+// Merge3 merges the changes made to the common ancestor base in ours and in
+// theirs, per channel and per field. Channels are matched by ID and then by
+// identifier. A field changed in both ways to different values is a
+// conflict and keeps our value. A channel removed on one side and changed
+// on the other is a conflict on the field ChannelConflict and is kept.
+func Merge3(base, ours, theirs *Directory) (*Directory, []Conflict) {
+	out := &Directory{Instance: ours.Instance}
+	var conflicts []Conflict
+
+	// match ours and theirs to base, and theirs additions to ours additions
+	oursOf := make(map[*Channel]*Channel)
+	theirsOf := make(map[*Channel]*Channel)
+	matchedO := make(map[*Channel]bool)
+	matchedT := make(map[*Channel]bool)
+	for _, b := range base.Channels {
+		if o := ours.Channels.match(b, matchedO); o != nil {
+			oursOf[b] = o
+			matchedO[o] = true
+		}
+		if t := theirs.Channels.match(b, matchedT); t != nil {
+			theirsOf[b] = t
+			matchedT[t] = true
+		}
+	}
+	baseOf := make(map[*Channel]*Channel)
+	for b, o := range oursOf {
+		baseOf[o] = b
+	}
+
+	for _, o := range ours.Channels {
+		b := baseOf[o]
+		if b == nil {
+			// added in ours, maybe in theirs too
+			t := theirs.Channels.match(o, matchedT)
+			if t == nil {
+				out.Channels = append(out.Channels, o.Clone())
+				continue
+			}
+			matchedT[t] = true
+			c, cs := merge3Channel(&Channel{}, o, t)
+			out.Channels = append(out.Channels, c)
+			conflicts = append(conflicts, cs...)
+			continue
+		}
+		t := theirsOf[b]
+		if t == nil {
+			// removed in theirs
+			if DiffChannel(b, o) != nil {
+				out.Channels = append(out.Channels, o.Clone())
+				conflicts = append(conflicts, Conflict{o.ID, ChannelConflict, []SourceValue{
+					{SourceOurs, Modified}, {SourceTheirs, Removed}}, Modified})
+			}
+			continue
+		}
+		c, cs := merge3Channel(b, o, t)
+		out.Channels = append(out.Channels, c)
+		conflicts = append(conflicts, cs...)
+	}
+
+	for _, b := range base.Channels {
+		t := theirsOf[b]
+		if oursOf[b] == nil && t != nil && DiffChannel(b, t) != nil {
+			// removed in ours, changed in theirs
+			out.Channels = append(out.Channels, t.Clone())
+			conflicts = append(conflicts, Conflict{t.ID, ChannelConflict, []SourceValue{
+				{SourceOurs, Removed}, {SourceTheirs, Modified}}, Modified})
+		}
+	}
+	for _, t := range theirs.Channels {
+		if !matchedT[t] {
+			out.Channels = append(out.Channels, t.Clone())
+		}
+	}
+	return out, conflicts
+}
+
+// merge3Channel merges the changes made to channel b in o and in t.
+func merge3Channel(b, o, t *Channel) (*Channel, []Conflict) {
+	c := o.Clone()
+	var conflicts []Conflict
+	for _, f := range Fields {
+		bv, _ := b.Get(f)
+		ov, _ := o.Get(f)
+		tv, _ := t.Get(f)
+		switch {
+		case ov == tv, tv == bv:
+		case ov == bv:
+			c.Set(f, tv)
+		default:
+			conflicts = append(conflicts, Conflict{o.ID, f, []SourceValue{
+				{SourceBase, bv}, {SourceOurs, ov}, {SourceTheirs, tv}}, ov})
+		}
+	}
+
+	baseFeeds, theirFeeds := b.feedURLs(), t.feedURLs()
+	feeds := Feeds{}
+	if o.Feeds != nil {
+		for _, f := range *o.Feeds {
+			k := feedKey(f)
+			if contains(baseFeeds, k) && !contains(theirFeeds, k) {
+				continue
+			}
+			feeds.Add(f)
+		}
+	}
+	if t.Feeds != nil {
+		for _, f := range *t.Feeds {
+			if !contains(baseFeeds, feedKey(f)) {
+				feeds.Add(f)
+			}
+		}
+	}
+	c.Feeds = &feeds
+	return c, conflicts
+}
@@ -0,0 +1,47 @@
+package emm
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := newDirectory(cd)
+	base.Add(&Channel{ID: "old", Identifier: "http://old.example/", Region: "Global"})
+	base.Add(&Channel{ID: "kept", Identifier: "http://kept.example/", Region: "Global"})
+
+	ours := newDirectory(cd)
+	ours.Channels[0].CountryCode = "FR"
+	*ours.Channels[0].Feeds = append(*ours.Channels[0].Feeds, Feed{Title: "news", URL: feedURL("http://www.malekal.com/news/feed/")})
+	ours.Add(&Channel{ID: "kept", Identifier: "http://kept.example/", Region: "Europe"})
+	ours.Add(&Channel{ID: "new", Identifier: "http://new.example/"})
+
+	theirs := newDirectory(cd)
+	theirs.Channels[0].Region = "Europe"
+	theirs.Channels[0].Feeds = &Feeds{}
+	theirs.Add(&Channel{ID: "old", Identifier: "http://old.example/", Region: "Global"})
+	theirs.Add(&Channel{ID: "kept", Identifier: "http://kept.example/", Region: "Asia"})
+
+	d, conflicts := Merge3(base, ours, theirs)
+	if len(d.Channels) != 3 {
+		t.Fatalf("Merge3() = %d channels; want 3", len(d.Channels))
+	}
+	c := d.Channels[0]
+	if c.CountryCode != "FR" || c.Region != "Europe" {
+		t.Errorf("Merge3() = %s, %s; want FR, Europe", c.CountryCode, c.Region)
+	}
+	// theirs removed the base feed, ours added one
+	if len(*c.Feeds) != 1 || (*c.Feeds)[0].Title != "news" {
+		t.Errorf("Merge3() feeds = %v; want news feed only", *c.Feeds)
+	}
+	if d.Channels[1].ID != "kept" || d.Channels[2].ID != "new" {
+		t.Errorf("Merge3() = %s, %s; want kept, new", d.Channels[1].ID, d.Channels[2].ID)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("Merge3() conflicts = %v; want 1", conflicts)
+	}
+	if cf := conflicts[0]; cf.Channel != "kept" || cf.Field != "region" || cf.Chosen != "Europe" {
+		t.Errorf("Merge3() conflict = %v; want kept region", cf)
+	}
+
+	if _, conflicts := Merge3(base, ours, base); len(conflicts) != 0 {
+		t.Errorf("Merge3() with unchanged theirs = %v; want no conflicts", conflicts)
+	}
+}
@@ -0,0 +1,54 @@
+package emm
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	a := newDirectory(cd)
+	b := newDirectory(cd)
+	b.Channels[0].ID = "malekal"
+	b.Channels[0].CountryCode = "FR"
+	b.Channels[0].Description = ""
+	*b.Channels[0].Feeds = append(*b.Channels[0].Feeds, Feed{Title: "news", URL: feedURL("http://www.malekal.com/news/feed/")})
+	b.Add(NewChannel(rssFeed, "Public"))
+
+	tests := []struct {
+		opts    MergeOptions
+		id      string
+		country string
+		err     bool
+	}{
+		{MergeOptions{Policy: FirstWins}, "P_malekalssite", "US", false},
+		{MergeOptions{Policy: LastWins}, "malekal", "FR", false},
+		{MergeOptions{Policy: FieldPrecedence, Precedence: map[string]int{"country": 1}}, "P_malekalssite", "FR", false},
+		{MergeOptions{Policy: Fail}, "P_malekalssite", "US", true},
+	}
+	for _, test := range tests {
+		d, conflicts, err := Merge(test.opts, a, b)
+		if (err != nil) != test.err {
+			t.Errorf("Merge(%s) error = %v; want error %v", test.opts.Policy, err, test.err)
+		}
+		if len(d.Channels) != 2 {
+			t.Fatalf("Merge(%s) = %d channels; want 2", test.opts.Policy, len(d.Channels))
+		}
+		c := d.Channels[0]
+		if c.ID != test.id || c.CountryCode != test.country {
+			t.Errorf("Merge(%s) = %s, %s; want %s, %s", test.opts.Policy, c.ID, c.CountryCode, test.id, test.country)
+		}
+		if c.Description != "malekals site" {
+			t.Errorf("Merge(%s).Description = %q; want malekals site", test.opts.Policy, c.Description)
+		}
+		if len(*c.Feeds) != 2 {
+			t.Errorf("Merge(%s) = %d feeds; want 2", test.opts.Policy, len(*c.Feeds))
+		}
+		if len(conflicts) != 2 || conflicts[0].Field != "id" || conflicts[1].Field != "country" {
+			t.Errorf("Merge(%s) conflicts = %v; want id and country", test.opts.Policy, conflicts)
+		}
+	}
+	// the inputs are left alone
+	if len(*a.Channels[0].Feeds) != 1 || a.Channels[0].CountryCode != "US" {
+		t.Errorf("Merge() modified its input")
+	}
+	if _, _, err := Merge(MergeOptions{MatchBy: []string{"title"}}, a, b); err == nil {
+		t.Errorf("Merge() with match key title returned no error")
+	}
+}
@@ -0,0 +1,142 @@
+package emm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A Profile holds values for the metadata fields of a channel. Empty fields
+// are not set by a profile.
+type Profile struct {
+	Format          string `json:"format,omitempty"`
+	Type            string `json:"type,omitempty"`
+	Subject         string `json:"subject,omitempty"`
+	Description     string `json:"description,omitempty"`
+	Encoding        string `json:"encoding,omitempty"`
+	CountryCode     string `json:"country,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Category        string `json:"category,omitempty"`
+	Ranking         int    `json:"ranking,omitempty"`
+	Language        string `json:"language,omitempty"`
+	UpdatePeriod    string `json:"updatePeriod,omitempty"`
+	UpdateFrequency int    `json:"updateFrequency,omitempty"`
+}
+
+// DefaultProfile holds the values used for new channels when no other
+// profile is given.
+var DefaultProfile = &Profile{
+	Format:          "rss",
+	Type:            "webnews",
+	Subject:         "eucert",
+	Encoding:        "UTF-8",
+	CountryCode:     "US",
+	Region:          "Global",
+	Category:        "Specialist",
+	Ranking:         1,
+	UpdatePeriod:    "daily",
+	UpdateFrequency: 4,
+}
+
+// Merge returns a new profile holding the fields of p overridden by the
+// non-empty fields of other.
+func (p *Profile) Merge(other *Profile) *Profile {
+	c := &Channel{}
+	p.Apply(c)
+	if other != nil {
+		other.Apply(c)
+	}
+	return c.Profile()
+}
+
+// Apply sets the non-empty fields of the profile on channel c.
+func (p *Profile) Apply(c *Channel) {
+	setString(&c.Format, p.Format)
+	setString(&c.Type, p.Type)
+	setString(&c.Subject, p.Subject)
+	setString(&c.Description, p.Description)
+	setString(&c.Encoding, p.Encoding)
+	setString(&c.CountryCode, p.CountryCode)
+	setString(&c.Region, p.Region)
+	setString(&c.Category, p.Category)
+	setString(&c.Language, p.Language)
+	setString(&c.UpdatePeriod, p.UpdatePeriod)
+	if p.Ranking != 0 {
+		c.Ranking = p.Ranking
+	}
+	if p.UpdateFrequency != 0 {
+		c.UpdateFrequency = p.UpdateFrequency
+	}
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+// Profile returns the metadata fields of the channel as a profile.
+func (e *Channel) Profile() *Profile {
+	return &Profile{
+		Format:          e.Format,
+		Type:            e.Type,
+		Subject:         e.Subject,
+		Description:     e.Description,
+		Encoding:        e.Encoding,
+		CountryCode:     e.CountryCode,
+		Region:          e.Region,
+		Category:        e.Category,
+		Ranking:         e.Ranking,
+		Language:        e.Language,
+		UpdatePeriod:    e.UpdatePeriod,
+		UpdateFrequency: e.UpdateFrequency,
+	}
+}
+
+// Set sets the profile field with the given name, as used in the channel
+// directory XML, to value.
+func (p *Profile) Set(field, value string) error {
+	if field == "id" || field == "identifier" || field == "disabled" {
+		return fmt.Errorf("Channel field %q can not be set by a profile", field)
+	}
+	c := &Channel{}
+	p.Apply(c)
+	if err := c.Set(field, value); err != nil {
+		return err
+	}
+	*p = *c.Profile()
+	return nil
+}
+
+// Profiles maps profile names to profiles.
+type Profiles map[string]*Profile
+
+// LoadProfiles reads named profiles from a JSON object mapping profile
+// names to profiles.
+func LoadProfiles(r io.Reader) (Profiles, error) {
+	p := Profiles{}
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("Could not load profiles: %s", err)
+	}
+	return p, nil
+}
+
+// ProfilesFromFile loads named profiles from a JSON file.
+func ProfilesFromFile(path string) (Profiles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadProfiles(f)
+}
+
+// Get returns the named profile merged over the profile base.
+func (ps Profiles) Get(name string, base *Profile) (*Profile, error) {
+	p, ok := ps[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown profile %q", name)
+	}
+	return base.Merge(p), nil
+}
@@ -0,0 +1,64 @@
+package emm
+
+import (
+	"strings"
+	"testing"
+)
+
+const profiles = `{
+	"be": {"country": "BE", "region": "Europe", "ranking": 2},
+	"gov": {"category": "Government", "subject": "gov"}
+}`
+
+func TestLoadProfiles(t *testing.T) {
+	ps, err := LoadProfiles(strings.NewReader(profiles))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ps.Get("be", DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CountryCode != "BE" || p.Region != "Europe" || p.Ranking != 2 {
+		t.Errorf("Get(be) = %+v; want BE, Europe, 2", p)
+	}
+	if p.Subject != DefaultProfile.Subject {
+		t.Errorf("Get(be).Subject = %q; want %q", p.Subject, DefaultProfile.Subject)
+	}
+	if _, err := ps.Get("nl", DefaultProfile); err == nil {
+		t.Errorf("Get(nl) returned no error")
+	}
+}
+
+func TestNewChannelProfile(t *testing.T) {
+	p := DefaultProfile.Merge(&Profile{CountryCode: "FR", Language: "fr"})
+	c := NewChannelProfile(rssFeed, "Public", p)
+	if c.CountryCode != "FR" {
+		t.Errorf("CountryCode = %q; want FR", c.CountryCode)
+	}
+	// the feed language takes precedence over the profile
+	if c.Language != "en" {
+		t.Errorf("Language = %q; want en", c.Language)
+	}
+	if c.Type != "webnews" {
+		t.Errorf("Type = %q; want webnews", c.Type)
+	}
+}
+
+func TestProfileSet(t *testing.T) {
+	p := &Profile{}
+	if err := p.Set("ranking", "3"); err != nil || p.Ranking != 3 {
+		t.Errorf("Set(ranking, 3) = %v, Ranking %d; want 3", err, p.Ranking)
+	}
+	if err := p.Set("ranking", "high"); err == nil {
+		t.Errorf("Set(ranking, high) returned no error")
+	}
+	if err := p.Set("colour", "red"); err == nil {
+		t.Errorf("Set(colour, red) returned no error")
+	}
+	c := &Channel{CountryCode: "US"}
+	p.Apply(c)
+	if c.CountryCode != "US" || c.Ranking != 3 {
+		t.Errorf("Apply() = %+v; want US, 3", c)
+	}
+}
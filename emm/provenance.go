@@ -0,0 +1,171 @@
+package emm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// Provenance records who added a channel, when and from which input, when
+// it was last changed, when its feeds last passed a check and whether it is
+// disabled. Times are nil if unknown.
+type Provenance struct {
+	ID          string     `json:"id"`
+	Added       *time.Time `json:"added,omitempty"`
+	AddedBy     string     `json:"addedBy,omitempty"`
+	AddedRun    string     `json:"addedRun,omitempty"`
+	Source      string     `json:"source,omitempty"`
+	Modified    *time.Time `json:"modified,omitempty"`
+	ModifiedBy  string     `json:"modifiedBy,omitempty"`
+	ModifiedRun string     `json:"modifiedRun,omitempty"`
+	// Checked is the last time the channel feeds were fetched and parsed
+	Checked *time.Time `json:"checked,omitempty"`
+	Removed *time.Time `json:"removed,omitempty"`
+	// Disabled is the time the channel was disabled, nil if enabled. The
+	// EMM XML leaves disabled channels out, so Channel keeps them until
+	// they are enabled again.
+	Disabled *time.Time     `json:"disabled,omitempty"`
+	Channel  *ChannelRecord `json:"channel,omitempty"`
+	// FormerIDs lists the IDs of the channel before migrations, oldest first
+	FormerIDs []string `json:"formerIDs,omitempty"`
+}
+
+// A ProvenanceStore holds the provenance of channels by channel ID. It is
+// stored as JSON object next to the directory.
+type ProvenanceStore map[string]*Provenance
+
+// ReadProvenance reads the provenance store at path. A missing store is
+// empty.
+func ReadProvenance(path string) (ProvenanceStore, error) {
+	s := make(ProvenanceStore)
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write replaces the provenance store at path.
+func (s ProvenanceStore) Write(path string) error {
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, append(buf, '\n'), 0644)
+}
+
+// Lookup returns the provenance of the channel with the given current or
+// former ID.
+func (s ProvenanceStore) Lookup(id string) *Provenance {
+	if p, ok := s[id]; ok {
+		return p
+	}
+	for _, p := range s {
+		for _, f := range p.FormerIDs {
+			if f == id {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// Apply adds the disabled channels of the store, which the EMM XML leaves
+// out, to d, ordered by ID.
+func (s ProvenanceStore) Apply(d *Directory) error {
+	have := make(map[string]bool)
+	for _, c := range d.Channels {
+		have[c.ID] = true
+	}
+	var ids []string
+	for id, p := range s {
+		if p.Disabled != nil && p.Channel != nil && !have[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c, err := s[id].Channel.Channel()
+		if err != nil {
+			return fmt.Errorf("Invalid disabled channel %s: %s", id, err)
+		}
+		c.Disabled = true
+		d.Channels = append(d.Channels, c)
+	}
+	return nil
+}
+
+func (s ProvenanceStore) get(id string) *Provenance {
+	p, ok := s[id]
+	if !ok {
+		p = &Provenance{ID: id}
+		s[id] = p
+	}
+	return p
+}
+
+// Sync updates the store with the changes journaled in entries and the
+// channels checked in run. Provenance follows channels whose ID changed.
+func (s ProvenanceStore) Sync(run *JournalRun, entries []*JournalEntry) {
+	// move renamed channels all at once, so that swapped IDs do not clash
+	renamed := make(map[string]*Provenance)
+	for _, e := range entries {
+		if e.Before == nil || e.After == nil || e.Before.ID == e.After.ID {
+			continue
+		}
+		p := s.get(e.Before.ID)
+		delete(s, e.Before.ID)
+		p.FormerIDs = append(p.FormerIDs, p.ID)
+		p.ID = e.After.ID
+		renamed[p.ID] = p
+	}
+	for id, p := range renamed {
+		s[id] = p
+	}
+
+	for _, e := range entries {
+		t := e.Time
+		switch e.Op {
+		case JournalAdd:
+			p := s.get(e.After.ID)
+			p.Added, p.AddedBy, p.AddedRun, p.Source = &t, e.Operator, e.Run, e.Source
+			p.Removed = nil
+			p.disable(e.After, t)
+		case JournalRemove:
+			p := s.get(e.Before.ID)
+			p.Removed, p.Disabled, p.Channel = &t, nil, nil
+		default:
+			p := s.get(e.After.ID)
+			p.Modified, p.ModifiedBy, p.ModifiedRun = &t, e.Operator, e.Run
+			p.disable(e.After, t)
+		}
+	}
+	if run != nil {
+		t := run.Time
+		for _, c := range run.Checked {
+			s.get(c.ID).Checked = &t
+		}
+	}
+}
+
+// disable records the disabled state of channel r, keeping the time of an
+// earlier disable, and keeps the channel while it is disabled.
+func (p *Provenance) disable(r *ChannelRecord, t time.Time) {
+	if !r.Disabled {
+		p.Disabled, p.Channel = nil, nil
+		return
+	}
+	if p.Disabled == nil {
+		p.Disabled = &t
+	}
+	p.Channel = r
+}
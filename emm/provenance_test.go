@@ -0,0 +1,62 @@
+package emm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	d := newDirectory(cd)
+	orig := d.Clone()
+	run := NewJournalRun("add", "stdin")
+	run.Operator = "alice"
+	added := NewChannel(rssFeed, "Public")
+	added.ID = "Added"
+	added.Identifier = "https://added.example.com/"
+	d.Add(added)
+	run.Sources[added] = "line 1"
+	run.Checked = Channels{added}
+
+	s := make(ProvenanceStore)
+	s.Sync(run, run.Entries(Track(orig, d)))
+	if p := s["Added"]; p == nil || p.AddedBy != "alice" || p.Source != "line 1" || p.Added == nil || p.Checked == nil {
+		t.Fatalf("Sync() of add = %+v; want added by alice from line 1 and checked", p)
+	}
+
+	before := d.Clone()
+	run = NewJournalRun("migrate", "")
+	run.Operator = "bob"
+	d.Channels[0].ID, d.Channels[1].ID = "First", "Renamed"
+	s.Sync(run, run.Entries(Track(before, d)))
+	p := s.Lookup("Added")
+	if p == nil || p.ID != "Renamed" || p.AddedBy != "alice" || p.ModifiedBy != "bob" || len(p.FormerIDs) != 1 {
+		t.Errorf("Lookup(former ID) = %+v; want Renamed added by alice, modified by bob", p)
+	}
+
+	// swapped IDs: provenance follows the channels, not the IDs
+	before = d.Clone()
+	d.Channels[0].ID, d.Channels[1].ID = "Renamed", "First"
+	s.Sync(run, run.Entries(Track(before, d)))
+	if p := s["First"]; p == nil || p.AddedBy != "alice" || p.Source != "line 1" {
+		t.Errorf("Sync() of swapped IDs gave First %+v; want the channel added by alice", p)
+	}
+	if p := s["Renamed"]; p == nil || p.AddedBy == "alice" {
+		t.Errorf("Sync() of swapped IDs gave Renamed %+v; want the other channel", p)
+	}
+
+	dir, err := ioutil.TempDir("", "emmchan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "channels.xml.provenance.json")
+	if err := s.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadProvenance(path)
+	if err != nil || len(read) != len(s) || read["First"].Source != "line 1" {
+		t.Errorf("ReadProvenance() = %v, %v; want the store written", read, err)
+	}
+}
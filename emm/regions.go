@@ -0,0 +1,32 @@
+package emm
+
+import "strings"
+
+// DefaultRegions maps ISO 3166 country codes to EMM regions.
+var DefaultRegions = make(map[string]string)
+
+var regionCountries = map[string]string{
+	"Europe": "AD AL AT BA BE BG BY CH CY CZ DE DK EE ES FI FO FR GB GG GI GR " +
+		"HR HU IE IM IS IT JE LI LT LU LV MC MD ME MK MT NL NO PL PT RO RS RU " +
+		"SE SI SK SM UA VA XK",
+	"North America": "BM CA GL MX PM US",
+	"Central America": "AG AI AW BB BL BQ BS BZ CR CU CW DM DO GD GP GT HN HT " +
+		"JM KN KY LC MF MQ MS NI PA PR SV SX TC TT VC VG VI",
+	"South America": "AR BO BR CL CO EC FK GF GY PE PY SR UY VE",
+	"Middle East":   "AE BH IL IQ IR JO KW LB OM PS QA SA SY TR YE",
+	"Africa": "AO BF BI BJ BW CD CF CG CI CM CV DJ DZ EG EH ER ET GA GH GM GN " +
+		"GQ GW KE KM LR LS LY MA MG ML MR MU MW MZ NA NE NG RE RW SC SD SH SL " +
+		"SN SO SS ST SZ TD TG TN TZ UG YT ZA ZM ZW",
+	"Asia": "AF AM AZ BD BN BT CN GE HK ID IN JP KG KH KP KR KZ LA LK MM MN MO " +
+		"MV MY NP PH PK SG TH TJ TL TM TW UZ VN",
+	"Oceania": "AS AU CK FJ FM GU KI MH MP NC NF NR NU NZ PF PG PN PW SB TK TO " +
+		"TV VU WF WS",
+}
+
+func init() {
+	for region, countries := range regionCountries {
+		for _, cc := range strings.Fields(countries) {
+			DefaultRegions[cc] = region
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package emm
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// hostname matches domain names with at least two labels and an alphabetic
+// top level domain.
+var hostname = regexp.MustCompile(`^(?i)(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}\.?$`)
+
+// fileName matches file names which look like host names without scheme.
+var fileName = regexp.MustCompile(`(?i)\.(?:xml|rss|atom|rdf|html?|php|aspx?|jsp|json|txt)$`)
+
+// NormalizeURL returns the http or https URL s in canonical form and
+// whether it differs from s, ignoring surrounding space. Bare domains and
+// hosts without scheme, such as example.com/feed, get the https scheme
+// unless they look like file names; an empty path becomes "/"; scheme and
+// host are lower-cased. URLs that can not be normalized safely are
+// rejected with an error.
+func NormalizeURL(s string) (string, bool, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return "", false, fmt.Errorf("Empty URL")
+	}
+	if !strings.Contains(raw, "://") {
+		host := raw
+		if i := strings.IndexAny(host, "/?#"); i >= 0 {
+			host = host[:i]
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !hostname.MatchString(host) && net.ParseIP(host) == nil || fileName.MatchString(host) {
+			return "", false, fmt.Errorf("Not a URL or host name: %q", raw)
+		}
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false, fmt.Errorf("Could not parse URL: %s", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false, fmt.Errorf("Unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" || u.User != nil {
+		return "", false, fmt.Errorf("Invalid URL %s", u)
+	}
+	host := u.Hostname()
+	if !hostname.MatchString(host) && net.ParseIP(host) == nil {
+		return "", false, fmt.Errorf("Invalid host name %q", host)
+	}
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	n := u.String()
+	return n, n != strings.TrimSpace(s), nil
+}
@@ -0,0 +1,32 @@
+package emm
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		changed bool
+	}{
+		{"https://www.example.com/feed/", "https://www.example.com/feed/", false},
+		{"  http://127.0.0.1:8080/rss.xml ", "http://127.0.0.1:8080/rss.xml", false},
+		{"https://example.com", "https://example.com/", true},
+		{"HTTP://Example.COM/Feed", "http://example.com/Feed", true},
+		{"example.com", "https://example.com/", true},
+		{"blog.example.co.uk/feed?format=rss", "https://blog.example.co.uk/feed?format=rss", true},
+		{"example.com:8443/rss", "https://example.com:8443/rss", true},
+	}
+	for _, test := range tests {
+		got, changed, err := NormalizeURL(test.in)
+		if err != nil || got != test.want || changed != test.changed {
+			t.Errorf("NormalizeURL(%q) = %q, %v, %v; want %q, %v", test.in, got, changed, err, test.want, test.changed)
+		}
+	}
+
+	for _, in := range []string{"", "localhost", "not a url", "ftp://example.com/feed",
+		"mailto:info@example.com", "https:///feed", "https://user:pw@example.com/", "exa_mple.com/feed", "feed.xml"} {
+		if got, _, err := NormalizeURL(in); err == nil {
+			t.Errorf("NormalizeURL(%q) = %q; want error", in, got)
+		}
+	}
+}
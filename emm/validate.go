@@ -0,0 +1,164 @@
+package emm
+
+import (
+	"fmt"
+	"net/url"
+
+	"golang.org/x/text/language"
+)
+
+// Severities of validation findings.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Range of valid channel rankings.
+const (
+	MinRanking = 1
+	MaxRanking = 5
+)
+
+// UpdatePeriods lists the allowed channel update periods.
+var UpdatePeriods = []string{"hourly", "daily", "weekly", "monthly", "yearly"}
+
+// A Finding is a problem found in a channel directory.
+type Finding struct {
+	Severity string `json:"severity"`
+	// ID of the channel, empty for findings about the whole directory
+	Channel string `json:"channel,omitempty"`
+	// Index of the channel in the directory
+	Index   int    `json:"index"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: channel %d (%s): %s: %s", f.Severity, f.Index, f.Channel, f.Field, f.Message)
+}
+
+// Findings is a list of validation findings.
+type Findings []Finding
+
+// Errors returns the number of findings with severity SeverityError.
+func (fs Findings) Errors() int {
+	n := 0
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate checks every channel of the directory and returns the problems
+// found.
+func (d *Directory) Validate() Findings {
+	d.Lock()
+	defer d.Unlock()
+	var fs Findings
+	ids := make(map[string]int)
+	identifiers := make(map[string]int)
+	for i, c := range d.Channels {
+		fs = append(fs, c.validate(i, d.Instance)...)
+		if j, ok := ids[c.ID]; ok && c.ID != "" {
+			fs = append(fs, Finding{SeverityError, c.ID, i, "id",
+				fmt.Sprintf("duplicate ID, also used by channel %d", j)})
+		} else {
+			ids[c.ID] = i
+		}
+		if j, ok := identifiers[c.Identifier]; ok && c.Identifier != "" {
+			fs = append(fs, Finding{SeverityWarning, c.ID, i, "identifier",
+				fmt.Sprintf("duplicate identifier, also used by channel %d", j)})
+		} else {
+			identifiers[c.Identifier] = i
+		}
+	}
+	return fs
+}
+
+func (e *Channel) validate(idx int, inst string) Findings {
+	var fs Findings
+	add := func(sev, field, format string, a ...interface{}) {
+		fs = append(fs, Finding{sev, e.ID, idx, field, fmt.Sprintf(format, a...)})
+	}
+
+	required := []struct {
+		field, value string
+	}{
+		{"id", e.ID},
+		{"format", e.Format},
+		{"type", e.Type},
+		{"subject", e.Subject},
+		{"identifier", e.Identifier},
+		{"country", e.CountryCode},
+		{"region", e.Region},
+		{"category", e.Category},
+		{"language", e.Language},
+		{"updatePeriod", e.UpdatePeriod},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			add(SeverityError, r.field, "missing")
+		}
+	}
+
+	if e.CountryCode != "" {
+		r, err := language.ParseRegion(e.CountryCode)
+		if err != nil || !r.IsCountry() {
+			add(SeverityError, "country", "%q is not an ISO 3166 country code", e.CountryCode)
+		} else if r.String() != e.CountryCode {
+			add(SeverityWarning, "country", "%q should be written %q", e.CountryCode, r.String())
+		}
+	}
+	if e.Language != "" {
+		code, err := NormalizeLanguage(e.Language)
+		if err != nil {
+			add(SeverityError, "language", "%q is not a BCP 47 language tag", e.Language)
+		} else if code != e.Language {
+			add(SeverityWarning, "language", "%q should be written %q", e.Language, code)
+		}
+	}
+	if e.Ranking < MinRanking || e.Ranking > MaxRanking {
+		add(SeverityError, "ranking", "%d is not between %d and %d", e.Ranking, MinRanking, MaxRanking)
+	}
+	if e.UpdatePeriod != "" && !contains(UpdatePeriods, e.UpdatePeriod) {
+		add(SeverityError, "updatePeriod", "%q is not one of %v", e.UpdatePeriod, UpdatePeriods)
+	}
+	if e.UpdateFrequency <= 0 {
+		add(SeverityError, "updateFrequency", "%d is not positive", e.UpdateFrequency)
+	}
+	if e.Identifier != "" && !isHTTPURL(e.Identifier) {
+		add(SeverityWarning, "identifier", "%q is not an absolute http(s) URL", e.Identifier)
+	}
+
+	if e.Feeds == nil || len(*e.Feeds) == 0 {
+		add(SeverityError, "feed", "missing")
+	} else {
+		for _, f := range *e.Feeds {
+			u := url.URL(f.URL)
+			if !isHTTPURL(u.String()) {
+				add(SeverityError, "feed", "%q is not an absolute http(s) URL", u.String())
+			}
+		}
+	}
+
+	for _, err := range instanceDef(inst).Violations(e) {
+		add(SeverityError, err.Field, "%s", err)
+	}
+	return fs
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package emm
+
+import (
+	"strings"
+	"testing"
+)
+
+const invalid = `<directory>
+	<channel id="A">
+		<format>rss</format><type>webnews</type><subject>eucert</subject>
+		<identifier>http://a.example/</identifier>
+		<country>XX</country><region>Global</region><category>Specialist</category>
+		<ranking>9</ranking><language>en-US</language>
+		<schedule><updatePeriod>often</updatePeriod><updateFrequency>0</updateFrequency></schedule>
+		<feed title="a" url="ftp://a.example/feed"/>
+	</channel>
+	<channel id="A">
+		<format>rss</format><type>webnews</type>
+		<identifier>http://b.example/</identifier>
+		<country>BE</country><region>Europe</region><category>Specialist</category>
+		<ranking>1</ranking><language>fr</language>
+		<schedule><updatePeriod>daily</updatePeriod><updateFrequency>2</updateFrequency></schedule>
+		<feed title="b" url="http://b.example/feed"/>
+	</channel>
+</directory>`
+
+func TestValidate(t *testing.T) {
+	if fs := newDirectory(cd).Validate(); len(fs) != 0 {
+		t.Errorf("Validate() = %v; want no findings", fs)
+	}
+
+	fs := NewDirectory(invalid).Validate()
+	want := []string{
+		"error: channel 0 (A): country",
+		"warning: channel 0 (A): language",
+		"error: channel 0 (A): ranking",
+		"error: channel 0 (A): updatePeriod",
+		"error: channel 0 (A): updateFrequency",
+		"error: channel 0 (A): feed",
+		"error: channel 1 (A): subject",
+		"error: channel 1 (A): id",
+	}
+	if len(fs) != len(want) {
+		t.Fatalf("Validate() = %v; want %d findings", fs, len(want))
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(fs[i].String(), w) {
+			t.Errorf("Validate()[%d] = %s; want %s", i, fs[i], w)
+		}
+	}
+	if fs.Errors() != 7 {
+		t.Errorf("Errors() = %d; want 7", fs.Errors())
+	}
+}
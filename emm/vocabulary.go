@@ -0,0 +1,170 @@
+package emm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// VocabularyFields lists the channel fields restricted by a vocabulary.
+var VocabularyFields = []string{"category", "subject", "type", "region"}
+
+// A Vocabulary maps channel field names to their allowed values. Fields
+// without an entry are not restricted.
+type Vocabulary map[string][]string
+
+// LoadVocabulary reads a vocabulary from a JSON object mapping field names
+// to lists of allowed values.
+func LoadVocabulary(r io.Reader) (Vocabulary, error) {
+	v := Vocabulary{}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("Could not load vocabulary: %s", err)
+	}
+	for field := range v {
+		if !contains(VocabularyFields, field) {
+			return nil, fmt.Errorf("Could not load vocabulary: field %q can not be restricted", field)
+		}
+	}
+	return v, nil
+}
+
+// VocabularyFromFile loads a vocabulary from a JSON file.
+func VocabularyFromFile(path string) (Vocabulary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadVocabulary(f)
+}
+
+// A TermError reports a channel field value missing from a vocabulary.
+type TermError struct {
+	Field string
+	Value string
+	// Suggestion is the closest allowed value, if any is close.
+	Suggestion string
+}
+
+func (e *TermError) Error() string {
+	msg := fmt.Sprintf("%s %q is not allowed", e.Field, e.Value)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// Allowed reports whether value is allowed for the named field.
+func (v Vocabulary) Allowed(field, value string) bool {
+	terms, ok := v[field]
+	return !ok || contains(terms, value)
+}
+
+// Check returns an error for every field of channel c whose value is not
+// in the vocabulary.
+func (v Vocabulary) Check(c *Channel) []*TermError {
+	var errs []*TermError
+	for _, field := range VocabularyFields {
+		value, _ := c.Get(field)
+		if !v.Allowed(field, value) {
+			errs = append(errs, &TermError{field, value, v.Suggest(field, value)})
+		}
+	}
+	return errs
+}
+
+// Suggest returns the allowed value of field closest to value, or "" if
+// none is close enough to be a likely typo.
+func (v Vocabulary) Suggest(field, value string) string {
+	best, bestDist := "", -1
+	lv := strings.ToLower(value)
+	for _, term := range v[field] {
+		d := editDistance(lv, strings.ToLower(term))
+		if bestDist == -1 || d < bestDist {
+			best, bestDist = term, d
+		}
+	}
+	if bestDist == -1 || bestDist > len([]rune(value))/3+1 {
+		return ""
+	}
+	return best
+}
+
+// Rename renames a term of the vocabulary, or removes it if the new term
+// is allowed already. It reports whether the vocabulary changed.
+func (v Vocabulary) Rename(field, old, new string) bool {
+	terms := v[field]
+	if !contains(terms, old) {
+		return false
+	}
+	var renamed []string
+	for _, term := range terms {
+		switch {
+		case term != old:
+			renamed = append(renamed, term)
+		case !contains(terms, new):
+			renamed = append(renamed, new)
+		}
+	}
+	v[field] = renamed
+	return true
+}
+
+// Dump writes the vocabulary to w as JSON object.
+func (v Vocabulary) Dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// RenameTerm sets the named field to new on every channel where it is old
+// and returns the number of channels changed.
+func (d *Directory) RenameTerm(field, old, new string) (int, error) {
+	if !contains(VocabularyFields, field) {
+		return 0, fmt.Errorf("Field %q is not a vocabulary field", field)
+	}
+	d.Lock()
+	defer d.Unlock()
+	n := 0
+	for _, c := range d.Channels {
+		if v, _ := c.Get(field); v == old {
+			c.Set(field, new)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// editDistance returns the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,88 @@
+package emm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const vocabulary = `{
+	"category": ["Specialist", "Government", "News"],
+	"subject": ["eucert"]
+}`
+
+func TestVocabularyCheck(t *testing.T) {
+	v, err := LoadVocabulary(strings.NewReader(vocabulary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Channel{Category: "Specialst", Subject: "eucert", Type: "anything"}
+	errs := v.Check(c)
+	if len(errs) != 1 {
+		t.Fatalf("Check() = %v; want 1 error", errs)
+	}
+	if errs[0].Field != "category" || errs[0].Suggestion != "Specialist" {
+		t.Errorf("Check() = %+v; want category error suggesting Specialist", errs[0])
+	}
+	if s := v.Suggest("category", "Sports"); s != "" {
+		t.Errorf("Suggest(category, Sports) = %q; want no suggestion", s)
+	}
+	if _, err := LoadVocabulary(strings.NewReader(`{"ranking": ["1"]}`)); err == nil {
+		t.Errorf("LoadVocabulary() with ranking returned no error")
+	}
+}
+
+func TestRenameTerm(t *testing.T) {
+	d := newDirectory(cd)
+	n, err := d.RenameTerm("category", "Specialist", "Specialists")
+	if err != nil || n != 1 {
+		t.Fatalf("RenameTerm() = %d, %v; want 1", n, err)
+	}
+	if d.Channels[0].Category != "Specialists" {
+		t.Errorf("Category = %q; want Specialists", d.Channels[0].Category)
+	}
+	if _, err := d.RenameTerm("id", "a", "b"); err == nil {
+		t.Errorf("RenameTerm(id) returned no error")
+	}
+}
+
+func TestVocabularyRename(t *testing.T) {
+	v, err := LoadVocabulary(strings.NewReader(vocabulary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Rename("category", "Specialist", "Specialists") || !v.Allowed("category", "Specialists") || v.Allowed("category", "Specialist") {
+		t.Errorf("Rename(Specialist, Specialists) = %v; want Specialists instead of Specialist", v["category"])
+	}
+	if !v.Rename("category", "News", "Government") || len(v["category"]) != 2 {
+		t.Errorf("Rename(News, Government) = %v; want News removed", v["category"])
+	}
+	if v.Rename("subject", "webnews", "news") {
+		t.Errorf("Rename() of a missing term changed the vocabulary")
+	}
+
+	var buf bytes.Buffer
+	if err := v.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if dumped, err := LoadVocabulary(strings.NewReader(buf.String())); err != nil || len(dumped["category"]) != 2 {
+		t.Errorf("LoadVocabulary(Dump()) = %v, %v", dumped, err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"webnews", "webnews", 0},
+		{"specialst", "specialist", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if d := editDistance(test.a, test.b); d != test.want {
+			t.Errorf("editDistance(%q, %q) = %d; want %d", test.a, test.b, d, test.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// OPML represents an OPML document.
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Head represents the head of an OPML document.
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Body represents the body of an OPML document.
+type Body struct {
+	Outlines []*Outline `xml:"outline"`
+}
+
+// Outline represents an outline element, either a feed or a folder of
+// outlines.
+type Outline struct {
+	Text     string     `xml:"text,attr"`
+	Title    string     `xml:"title,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	XMLURL   string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string     `xml:"htmlUrl,attr,omitempty"`
+	Language string     `xml:"language,attr,omitempty"`
+	Outlines []*Outline `xml:"outline"`
+}
+
+// NewOPML creates a new OPML document from a given byte slice.
+func NewOPML(buf []byte) (*OPML, error) {
+	o := OPML{}
+	if err := xml.Unmarshal(buf, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Write writes the document to w as indented XML with an XML declaration.
+func (o *OPML) Write(w io.Writer) error {
+	out, err := xml.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// Walk calls fn for every outline of the document, depth first, with the
+// texts of the outlines enclosing it, outermost first.
+func (o *OPML) Walk(fn func(folders []string, o *Outline)) {
+	walk(nil, o.Body.Outlines, fn)
+}
+
+func walk(folders []string, outlines []*Outline, fn func([]string, *Outline)) {
+	for _, o := range outlines {
+		fn(folders, o)
+		if len(o.Outlines) > 0 {
+			walk(append(folders[:len(folders):len(folders)], o.Text), o.Outlines, fn)
+		}
+	}
+}
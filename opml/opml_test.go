@@ -0,0 +1,67 @@
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Security feeds</title></head>
+  <body>
+    <outline text="Blogs">
+      <outline text="Research Blog" type="rss" xmlUrl="http://feeds.feedburner.com/zscaler/research" htmlUrl="https://www.zscaler.com/" language="en"/>
+      <outline text="Vendors">
+        <outline text="malekals site" type="rss" xmlUrl="http://www.malekal.com/feed/"/>
+      </outline>
+    </outline>
+  </body>
+</opml>`
+
+func TestNewOPML(t *testing.T) {
+	o, err := NewOPML([]byte(doc))
+	if err != nil {
+		t.Fatalf("NewOPML() returned error %s", err)
+	}
+	if o.Version != "2.0" || o.Head.Title != "Security feeds" || len(o.Body.Outlines) != 1 {
+		t.Fatalf("NewOPML() = %+v", o)
+	}
+	blogs := o.Body.Outlines[0]
+	if len(blogs.Outlines) != 2 || blogs.Outlines[0].XMLURL != "http://feeds.feedburner.com/zscaler/research" ||
+		blogs.Outlines[0].Language != "en" || blogs.Outlines[1].Outlines[0].Text != "malekals site" {
+		t.Errorf("NewOPML() outlines = %+v", blogs)
+	}
+
+	var b bytes.Buffer
+	if err := o.Write(&b); err != nil {
+		t.Fatalf("Write() returned error %s", err)
+	}
+	o2, err := NewOPML(b.Bytes())
+	if err != nil || o2.Body.Outlines[0].Outlines[1].Outlines[0].XMLURL != "http://www.malekal.com/feed/" {
+		t.Errorf("NewOPML(Write()) = %+v, %v", o2, err)
+	}
+}
+
+func TestNewOPMLInvalid(t *testing.T) {
+	if _, err := NewOPML([]byte("<opml><body>")); err == nil {
+		t.Errorf("NewOPML() of truncated document returned no error")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	o, err := NewOPML([]byte(doc))
+	if err != nil {
+		t.Fatalf("NewOPML() returned error %s", err)
+	}
+	var got []string
+	o.Walk(func(folders []string, o *Outline) {
+		if o.XMLURL != "" {
+			got = append(got, strings.Join(append(folders, o.Text), "/"))
+		}
+	})
+	want := []string{"Blogs/Research Blog", "Blogs/Vendors/malekals site"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Walk() visited %v; want %v", got, want)
+	}
+}
@@ -5,13 +5,25 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/text/encoding/charmap"
 )
 
-// Feed represents the RSS feed.
+// Feed formats, named after the root element of the feed document.
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatRDF  = "rdf"
+)
+
+// Feed represents the RSS feed. Atom and RSS 1.0 (RDF) feeds are read into
+// the same structure.
 type Feed struct {
-	XMLName  xml.Name `xml:"rss"`
+	XMLName xml.Name `xml:"rss"`
+	// Format is the feed format, detected from the root element
+	Format string `xml:"-"`
+	// Encoding is the declared character encoding of the feed
 	Encoding string   `xml:"encoding,attr"`
 	Channel  *Channel `xml:"channel"`
 }
@@ -27,7 +39,7 @@ type Channel struct {
 	Language      string   `xml:"language"`
 	PubDate       string   `xml:"pubDate"`
 	LastBuildDate string   `xml:"lastBuildDate"`
-	Items         []Item
+	Items         []Item   `xml:"item"`
 }
 
 // Item represents a channel item
@@ -41,14 +53,180 @@ type Item struct {
 	Content     string `xml:"content"`
 }
 
+// atomFeed is an Atom feed document.
+type atomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    string     `xml:"author>name"`
+	ID        string     `xml:"id"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+}
+
+// alternate returns the first alternate link, the default relation.
+func alternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func (a *atomFeed) feed() *Feed {
+	c := &Channel{
+		Title:         a.Title,
+		Description:   a.Subtitle,
+		Language:      a.Lang,
+		PubDate:       a.Updated,
+		LastBuildDate: a.Updated,
+	}
+	if l := alternate(a.Links); l != "" {
+		c.Links = []string{l}
+	}
+	for _, e := range a.Entries {
+		i := Item{
+			Title:       e.Title,
+			Link:        alternate(e.Links),
+			PubDate:     e.Published,
+			Creator:     e.Author,
+			GUID:        e.ID,
+			Description: e.Summary,
+			Content:     e.Content,
+		}
+		if i.PubDate == "" {
+			i.PubDate = e.Updated
+		}
+		c.Items = append(c.Items, i)
+	}
+	return &Feed{Channel: c}
+}
+
+// rdfFeed is an RSS 1.0 feed document, whose items follow the channel.
+type rdfFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"language"`
+		Date        string `xml:"date"`
+	} `xml:"channel"`
+	Items []struct {
+		About       string `xml:"about,attr"`
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Creator     string `xml:"creator"`
+		Date        string `xml:"date"`
+	} `xml:"item"`
+}
+
+func (r *rdfFeed) feed() *Feed {
+	c := &Channel{
+		Title:         r.Channel.Title,
+		Links:         []string{r.Channel.Link},
+		Description:   r.Channel.Description,
+		Language:      r.Channel.Language,
+		PubDate:       r.Channel.Date,
+		LastBuildDate: r.Channel.Date,
+	}
+	for _, i := range r.Items {
+		c.Items = append(c.Items, Item{
+			Title:       i.Title,
+			Link:        i.Link,
+			PubDate:     i.Date,
+			Creator:     i.Creator,
+			GUID:        i.About,
+			Description: i.Description,
+		})
+	}
+	return &Feed{Channel: c}
+}
+
 // NewFeed creates a new Feed from a given byte slice and returns a
 // pointer to it.
 func NewFeed(buf []byte) (*Feed, error) {
-	f := Feed{}
-	d := xml.NewDecoder(bytes.NewReader(buf))
+	return NewFeedCharset(buf, "")
+}
+
+// NewFeedCharset creates a new Feed from a given byte slice, such as an
+// HTTP response body with the charset of its Content-Type. The charset is
+// used when the feed declares no encoding and is known.
+func NewFeedCharset(buf []byte, charset string) (*Feed, error) {
+	declared := declaredEncoding(buf)
+	var r io.Reader = bytes.NewReader(buf)
+	if declared == "" && charset != "" && !isUTF8(charset) {
+		if cr, err := makeCharsetReader(charset, r); err == nil {
+			r = cr
+		} else {
+			charset = ""
+		}
+	}
+	d := xml.NewDecoder(r)
 	d.CharsetReader = makeCharsetReader
-	if err := d.Decode(&f); err != nil {
-		return nil, err
+	var start xml.StartElement
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			start = se
+			break
+		}
+	}
+
+	var f *Feed
+	switch start.Name.Local {
+	case "rss":
+		f = &Feed{}
+		if err := d.DecodeElement(f, &start); err != nil {
+			return nil, err
+		}
+		f.Format = FormatRSS
+	case "feed":
+		a := &atomFeed{}
+		if err := d.DecodeElement(a, &start); err != nil {
+			return nil, err
+		}
+		f = a.feed()
+		f.XMLName = start.Name
+		f.Format = FormatAtom
+	case "RDF":
+		rd := &rdfFeed{}
+		if err := d.DecodeElement(rd, &start); err != nil {
+			return nil, err
+		}
+		f = rd.feed()
+		f.XMLName = start.Name
+		f.Format = FormatRDF
+	default:
+		return nil, fmt.Errorf("expected element type <rss>, <feed> or <RDF> but have <%s>", start.Name.Local)
+	}
+	if f.Channel == nil {
+		return nil, fmt.Errorf("No channel in %s feed", f.Format)
+	}
+	switch {
+	case declared != "":
+		f.Encoding = declared
+	case f.Encoding == "":
+		f.Encoding = charset
 	}
 	for _, l := range f.Channel.Links {
 		if l != "" {
@@ -56,15 +234,45 @@ func NewFeed(buf []byte) (*Feed, error) {
 			break
 		}
 	}
-	return &f, nil
+	return f, nil
+}
+
+// declaredEncoding returns the encoding of the XML declaration of buf.
+func declaredEncoding(buf []byte) string {
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	buf = bytes.TrimLeft(buf, " \t\r\n")
+	if !bytes.HasPrefix(buf, []byte("<?xml")) {
+		return ""
+	}
+	end := bytes.Index(buf, []byte("?>"))
+	if end < 0 {
+		return ""
+	}
+	decl := string(buf[:end])
+	i := strings.Index(decl, "encoding=")
+	if i < 0 {
+		return ""
+	}
+	v := decl[i+len("encoding="):]
+	if v == "" || (v[0] != '"' && v[0] != '\'') {
+		return ""
+	}
+	if j := strings.IndexByte(v[1:], v[0]); j >= 0 {
+		return v[1 : j+1]
+	}
+	return ""
+}
+
+func isUTF8(charset string) bool {
+	return strings.EqualFold(charset, "UTF-8") || strings.EqualFold(charset, "utf8")
 }
 
 func makeCharsetReader(charset string, input io.Reader) (io.Reader, error) {
-	if charset == "ISO-8859-1" || charset == "Windows-1252" {
+	if strings.EqualFold(charset, "ISO-8859-1") || strings.EqualFold(charset, "Windows-1252") {
 		// Windows-1252 is a superset of ISO-8859-1, so should do here
 		return charmap.Windows1252.NewDecoder().Reader(input), nil
 	}
-	if charset == "Windows-1255" {
+	if strings.EqualFold(charset, "Windows-1255") {
 		return charmap.Windows1255.NewDecoder().Reader(input), nil
 	}
 	return nil, fmt.Errorf("Unknown charset: %s", charset)
@@ -3,6 +3,8 @@ package rss
 import (
 	"encoding/xml"
 	"testing"
+
+	"golang.org/x/text/encoding/charmap"
 )
 
 var tests = []struct {
@@ -71,9 +73,87 @@ func TestNewFeed(t *testing.T) {
 		buf := []byte(test.in)
 		_, err := NewFeed(buf)
 		if err != nil {
-			if err.Error() != "expected element type <rss> but have <html>" {
+			if err.Error() != "expected element type <rss>, <feed> or <RDF> but have <html>" {
 				t.Errorf("%s", err)
 			}
 		}
 	}
 }
+
+func latin1(t *testing.T, s string) []byte {
+	buf, err := charmap.ISO8859_1.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestNewFeedAtom(t *testing.T) {
+	buf := latin1(t, `<?xml version="1.0" encoding="ISO-8859-1"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="fr">
+  <title>Actualités</title>
+  <subtitle>Sécurité informatique</subtitle>
+  <link rel="self" href="https://exemple.fr/atom.xml"/>
+  <link href="https://exemple.fr/"/>
+  <updated>2017-10-04T03:54:41Z</updated>
+  <entry>
+    <title>Élection</title>
+    <link rel="alternate" href="https://exemple.fr/election"/>
+    <id>urn:uuid:1</id>
+    <updated>2017-10-04T03:54:41Z</updated>
+    <author><name>Rédaction</name></author>
+  </entry>
+</feed>`)
+	f, err := NewFeed(buf)
+	if err != nil {
+		t.Fatalf("NewFeed() returned error %s", err)
+	}
+	if f.Format != FormatAtom || f.Encoding != "ISO-8859-1" {
+		t.Errorf("NewFeed() format %q, encoding %q; want atom, ISO-8859-1", f.Format, f.Encoding)
+	}
+	c := f.Channel
+	if c.Title != "Actualités" || c.Description != "Sécurité informatique" || c.Link != "https://exemple.fr/" || c.Language != "fr" {
+		t.Errorf("NewFeed() channel = %+v", c)
+	}
+	if len(c.Items) != 1 || c.Items[0].Title != "Élection" || c.Items[0].Link != "https://exemple.fr/election" ||
+		c.Items[0].Creator != "Rédaction" || c.Items[0].PubDate != "2017-10-04T03:54:41Z" {
+		t.Errorf("NewFeed() items = %+v", c.Items)
+	}
+}
+
+func TestNewFeedRDF(t *testing.T) {
+	f, err := NewFeed([]byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.org/">
+    <title>Example</title>
+    <link>https://example.org/</link>
+    <description>An example</description>
+    <dc:language>en</dc:language>
+  </channel>
+  <item rdf:about="https://example.org/1">
+    <title>One</title>
+    <link>https://example.org/1</link>
+  </item>
+</rdf:RDF>`))
+	if err != nil {
+		t.Fatalf("NewFeed() returned error %s", err)
+	}
+	if f.Format != FormatRDF || f.Encoding != "" || f.Channel.Title != "Example" || f.Channel.Link != "https://example.org/" ||
+		f.Channel.Language != "en" || len(f.Channel.Items) != 1 || f.Channel.Items[0].GUID != "https://example.org/1" {
+		t.Errorf("NewFeed() = %+v, channel %+v", f, f.Channel)
+	}
+}
+
+func TestNewFeedCharset(t *testing.T) {
+	buf := latin1(t, `<rss version="2.0"><channel><title>Actualités</title></channel></rss>`)
+	f, err := NewFeedCharset(buf, "iso-8859-1")
+	if err != nil {
+		t.Fatalf("NewFeedCharset() returned error %s", err)
+	}
+	if f.Format != FormatRSS || f.Encoding != "iso-8859-1" || f.Channel.Title != "Actualités" {
+		t.Errorf("NewFeedCharset() format %q, encoding %q, title %q", f.Format, f.Encoding, f.Channel.Title)
+	}
+	if f, err := NewFeedCharset([]byte(`<rss><channel><title>x</title></channel></rss>`), "x-unknown"); err != nil || f.Encoding != "" {
+		t.Errorf("NewFeedCharset() with unknown charset = %+v, %v; want no encoding", f, err)
+	}
+}